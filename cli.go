@@ -0,0 +1,355 @@
+//go:build !test
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"PakuchiBot/internal/bot"
+	"PakuchiBot/internal/repository"
+	"PakuchiBot/internal/scheduler"
+	"PakuchiBot/internal/storage"
+	"PakuchiBot/internal/utils"
+)
+
+const usage = `Usage: PakuchiBot <command> [arguments]
+
+Commands:
+  run                        run the bot (default)
+  migrate up|down [n]|status apply, revert or list database migrations
+  rotate-keys                re-encrypt stored tokens with the current encryption key
+  users list                 list bound MGClub users
+  users delete <qq>          delete a bound user and their notify setting
+  sign run --user <qq>       sign in one user right away
+  config validate            check the config file and exit
+  export [-o file]           export the database as JSON (stdout by default)
+  import <file>              replace the database contents with a JSON export ("-" reads stdin)
+`
+
+func runCommand(args []string) error {
+	switch args[0] {
+	case "run":
+		runBot()
+		return nil
+	case "migrate":
+		return runMigrate(args[1:])
+	case "rotate-keys":
+		return rotateKeys()
+	case "users":
+		return runUsers(args[1:])
+	case "sign":
+		return runSign(args[1:])
+	case "config":
+		return runConfig(args[1:])
+	case "export":
+		return runExport(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+	}
+}
+
+// initStorage loads the config, applies migrations and sets up the repositories without connecting to OneBot
+func initStorage() error {
+	if err := bot.InitConfig(); err != nil {
+		return fmt.Errorf("failed to init config: %w", err)
+	}
+	return nil
+}
+
+// rotateKeys re-encrypts all stored tokens with the current encryption key
+func rotateKeys() error {
+	if err := initStorage(); err != nil {
+		return err
+	}
+	defer storage.CloseDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	rotated, err := bot.UserRepo.RotateTokens(ctx, bot.TokenCrypto.Reencrypt, bot.TokenCrypto.NeedsRotation)
+	if err != nil {
+		return fmt.Errorf("failed to rotate keys: %w", err)
+	}
+
+	fmt.Printf("re-encrypted %d tokens with key %q\n", rotated, bot.TokenCrypto.CurrentKeyID())
+	return nil
+}
+
+// runMigrate handles `migrate up|down [steps]|status`, it opens the database without migrating it first
+func runMigrate(args []string) error {
+	if err := bot.LoadConfig(); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := bot.OpenStorage(); err != nil {
+		return err
+	}
+	defer storage.CloseDB()
+
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := storage.MigrateUp()
+		if err != nil {
+			return fmt.Errorf("failed to migrate up: %w", err)
+		}
+		fmt.Printf("applied %d migrations\n", len(applied))
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid steps: %s", args[1])
+			}
+			steps = n
+		}
+
+		reverted, err := storage.MigrateDown(steps)
+		if err != nil {
+			return fmt.Errorf("failed to migrate down: %w", err)
+		}
+		fmt.Printf("reverted %d migrations\n", len(reverted))
+
+	case "status":
+		statuses, err := storage.MigrationStatuses()
+		if err != nil {
+			return fmt.Errorf("failed to get migration status: %w", err)
+		}
+
+		for _, s := range statuses {
+			state := "pending"
+			switch {
+			case s.Missing:
+				state = "missing"
+			case s.Modified:
+				state = "modified"
+			case s.Applied:
+				state = "applied"
+			}
+
+			appliedAt := ""
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-9s %-40s %s\n", state, s.Version, appliedAt)
+		}
+
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down [steps] or status", command)
+	}
+
+	return nil
+}
+
+func runUsers(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected users list or users delete <qq>")
+	}
+
+	if err := initStorage(); err != nil {
+		return err
+	}
+	defer storage.CloseDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch args[0] {
+	case "list":
+		users, err := bot.UserRepo.GetAllUsers(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%-12s %-10s %-12s %-20s %s\n", "QQ", "KEY", "NOTIFY", "CREATED", "UPDATED")
+		for _, user := range users {
+			notify := "-"
+			setting, err := bot.NotifyRepo.GetSetting(ctx, user.UserID)
+			switch {
+			case err == nil && setting.GroupID != nil:
+				notify = fmt.Sprintf("%d", *setting.GroupID)
+			case err == nil:
+				notify = "private"
+			case !errors.Is(err, repository.ErrUserNotFound):
+				return err
+			}
+
+			keyID := utils.KeyID(user.Token)
+			if keyID == "" {
+				keyID = "legacy"
+			}
+
+			fmt.Printf("%-12s %-10s %-12s %-20s %s\n",
+				user.UserID,
+				keyID,
+				notify,
+				user.CreatedAt.Format("2006-01-02 15:04:05"),
+				user.UpdatedAt.Format("2006-01-02 15:04:05"))
+		}
+		fmt.Printf("%d users\n", len(users))
+
+	case "delete":
+		if len(args) != 2 {
+			return errors.New("expected users delete <qq>")
+		}
+
+		if err := bot.UserRepo.Delete(ctx, args[1]); err != nil {
+			if errors.Is(err, repository.ErrUserNotFound) {
+				return fmt.Errorf("user %s is not bound", args[1])
+			}
+			return err
+		}
+		fmt.Printf("deleted user %s\n", args[1])
+
+	default:
+		return fmt.Errorf("unknown users command %q, expected list or delete", args[0])
+	}
+
+	return nil
+}
+
+func runSign(args []string) error {
+	if len(args) == 0 || args[0] != "run" {
+		return errors.New("expected sign run --user <qq>")
+	}
+
+	flags := flag.NewFlagSet("sign run", flag.ContinueOnError)
+	userID := flags.String("user", "", "QQ of the user to sign in")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *userID == "" {
+		return errors.New("--user is required")
+	}
+
+	if err := initStorage(); err != nil {
+		return err
+	}
+	defer storage.CloseDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// no OneBot connection here, so the result is printed instead of sent to the user
+	task := scheduler.NewSignTask(bot.UserRepo, bot.SignRepo, bot.NotifyRepo, bot.TokenCrypto, nil, bot.Config.Scheduler.MaxRetries, bot.Config.Scheduler.SignConcurrency, bot.Config.DefaultSignWindow(), bot.Config.Location())
+	if err := task.RunUser(ctx, *userID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return fmt.Errorf("user %s is not bound", *userID)
+		}
+		return fmt.Errorf("failed to sign in user %s: %w", *userID, err)
+	}
+
+	fmt.Printf("signed in user %s\n", *userID)
+	return nil
+}
+
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return errors.New("expected config validate")
+	}
+
+	if err := bot.LoadConfig(); err != nil {
+		return err
+	}
+
+	fmt.Println("config is valid")
+	return nil
+}
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", "", "write to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := initStorage(); err != nil {
+		return err
+	}
+	defer storage.CloseDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	dump, err := storage.Export(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to export database: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dump); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	return nil
+}
+
+func runImport(args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected import <file>")
+	}
+
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", args[0], err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var dump storage.Dump
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&dump); err != nil {
+		return fmt.Errorf("failed to parse export: %w", err)
+	}
+
+	if err := initStorage(); err != nil {
+		return err
+	}
+	defer storage.CloseDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := storage.Import(ctx, &dump); err != nil {
+		return fmt.Errorf("failed to import database: %w", err)
+	}
+
+	rows := 0
+	for _, records := range dump.Tables {
+		rows += len(records)
+	}
+	fmt.Printf("imported %d rows into %d tables\n", rows, len(dump.Tables))
+	return nil
+}
@@ -4,6 +4,7 @@ go 1.23.0
 
 require (
 	github.com/fogleman/gg v1.3.0
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/google/go-github/v45 v45.2.0
 	github.com/jmoiron/sqlx v1.3.5
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
@@ -17,7 +18,6 @@ require (
 	github.com/FloatTech/ttl v0.0.0-20240716161252-965925764562 // indirect
 	github.com/RomiChan/websocket v1.4.3-0.20220227141055-9b2c6168c9c5 // indirect
 	github.com/dustin/go-humanize v1.0.1 // indirect
-	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/google/uuid v1.5.0 // indirect
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"PakuchiBot/internal/repository"
+	"PakuchiBot/internal/scheduler"
 	"PakuchiBot/internal/storage"
 	"PakuchiBot/internal/utils"
 
@@ -22,8 +23,9 @@ import (
 
 type BotConfig struct {
 	Connection struct {
-		WSAddress   string `mapstructure:"ws_address"`
-		AccessToken string `mapstructure:"access_token"`
+		WSAddress       string `mapstructure:"ws_address"`
+		AccessToken     string `mapstructure:"access_token"`
+		AccessTokenFile string `mapstructure:"access_token_file"`
 	} `mapstructure:"connection"`
 	Bot struct {
 		SelfID        int64    `mapstructure:"self_id"`
@@ -34,32 +36,67 @@ type BotConfig struct {
 		SuperUsers    []int64  `mapstructure:"super_users"`
 	} `mapstructure:"bot"`
 	Storage struct {
-		DBPath        string `mapstructure:"db_path"`
-		EncryptionKey string `mapstructure:"encryption_key"`
+		DBPath            string                `mapstructure:"db_path"`
+		EncryptionKey     string                `mapstructure:"encryption_key"`
+		EncryptionKeyFile string                `mapstructure:"encryption_key_file"`
+		EncryptionKeyID   string                `mapstructure:"encryption_key_id"`
+		OldEncryptionKeys []utils.EncryptionKey `mapstructure:"old_encryption_keys"`
 	} `mapstructure:"storage"`
 	Scheduler struct {
-		CheckInterval int `mapstructure:"check_interval"`
-		MaxRetries    int `mapstructure:"max_retries"`
+		CheckInterval     int               `mapstructure:"check_interval"`
+		MaxRetries        int               `mapstructure:"max_retries"`
+		SignConcurrency   int               `mapstructure:"sign_concurrency"`
+		SignRateLimit     float64           `mapstructure:"sign_rate_limit"`
+		DefaultSignWindow string            `mapstructure:"default_sign_window"`
+		Timezone          string            `mapstructure:"timezone"`
+		Jobs              map[string]string `mapstructure:"jobs"`
 	} `mapstructure:"scheduler"`
 	GitHub struct {
 		Enabled      bool   `mapstructure:"enabled"`
 		Interval     int    `mapstructure:"interval"`
 		Token        string `mapstructure:"token"`
+		TokenFile    string `mapstructure:"token_file"`
 		Repositories []struct {
-			Owner       string   `mapstructure:"owner"`
-			Name        string   `mapstructure:"name"`
-			MonitorType []string `mapstructure:"monitor_type"`
+			Owner         string   `mapstructure:"owner"`
+			Name          string   `mapstructure:"name"`
+			MonitorType   []string `mapstructure:"monitor_type"`
+			WebhookSecret string   `mapstructure:"webhook_secret"`
+			Source        string   `mapstructure:"source"`
+			BaseURL       string   `mapstructure:"base_url"`
+			Token         string   `mapstructure:"token"`
+
+			Branches        []string `mapstructure:"branches"`
+			NotifyRecovered bool     `mapstructure:"notify_recovered"`
 		} `mapstructure:"repositories"`
 		NotifyTargets []struct {
-			Type  string   `mapstructure:"type"`
-			ID    int64    `mapstructure:"id"`
-			Repos []string `mapstructure:"repos"`
+			Type     string   `mapstructure:"type"`
+			ID       int64    `mapstructure:"id"`
+			Repos    []string `mapstructure:"repos"`
+			Format   string   `mapstructure:"format"`
+			Digest   string   `mapstructure:"digest"`
+			Events   []string `mapstructure:"events"`
+			Branches []string `mapstructure:"branches"`
+			Labels   []string `mapstructure:"labels"`
 		} `mapstructure:"notify_targets"`
+		Webhook struct {
+			Enabled        bool   `mapstructure:"enabled"`
+			Listen         string `mapstructure:"listen"`
+			Path           string `mapstructure:"path"`
+			Secret         string `mapstructure:"secret"`
+			SecretFile     string `mapstructure:"secret_file"`
+			DisablePolling bool   `mapstructure:"disable_polling"`
+		} `mapstructure:"webhook"`
+		DigestTime string `mapstructure:"digest_time"`
+		Unfurl     struct {
+			Enabled bool    `mapstructure:"enabled"`
+			Groups  []int64 `mapstructure:"groups"`
+		} `mapstructure:"unfurl"`
 	} `mapstructure:"github"`
 	HumanLike struct {
 		Enabled bool `mapstructure:"enabled"`
 		LLM     struct {
 			APIKey      string  `mapstructure:"api_key"`
+			APIKeyFile  string  `mapstructure:"api_key_file"`
 			BaseURL     string  `mapstructure:"base_url"`
 			Model       string  `mapstructure:"model"`
 			Temperature float64 `mapstructure:"temperature"`
@@ -72,10 +109,13 @@ type BotConfig struct {
 			MaxTokens   int     `mapstructure:"max_tokens"`
 			BaseURL     string  `mapstructure:"base_url"`
 			APIKey      string  `mapstructure:"api_key"`
+			APIKeyFile  string  `mapstructure:"api_key_file"`
 		} `mapstructure:"vision"`
 		Behavior struct {
-			MinTypingSpeed       int     `mapstructure:"min_typing_speed"`
-			MaxTypingSpeed       int     `mapstructure:"max_typing_speed"`
+			MinTypingSpeed int `mapstructure:"min_typing_speed"`
+			MaxTypingSpeed int `mapstructure:"max_typing_speed"`
+
+			// deprecated, imported into plugin settings once, use /plugin instead
 			EnableGroupWhitelist bool    `mapstructure:"enable_group_whitelist"`
 			GroupWhitelist       []int64 `mapstructure:"group_whitelist"`
 		} `mapstructure:"behavior"`
@@ -83,13 +123,50 @@ type BotConfig struct {
 }
 
 var (
-	Config      BotConfig
-	DB          *sqlx.DB
-	UserRepo    *repository.UserRepository
-	NotifyRepo  *repository.NotifyRepository
-	TokenCrypto *utils.TokenCrypto
+	Config           BotConfig
+	DB               *sqlx.DB
+	UserRepo         *repository.UserRepository
+	NotifyRepo       *repository.NotifyRepository
+	SignRepo         *repository.SignRepository
+	LuckRepo         *repository.LuckRepository
+	PluginRepo       *repository.PluginRepository
+	JobRepo          *repository.JobRepository
+	GithubRepo       *repository.GithubSubscriptionRepository
+	GithubCursorRepo *repository.GithubCursorRepository
+	GithubWatchRepo  *repository.GithubWatchRepository
+	GithubDigestRepo *repository.GithubDigestRepository
+	TokenCrypto      *utils.TokenCrypto
 )
 
+// DefaultSignWindow is the sign-in window for users who haven't set their own, the zero window signs in right away
+func (c *BotConfig) DefaultSignWindow() scheduler.SignWindow {
+	// checked by Validate, so a parse error can't happen here
+	window, _ := scheduler.ParseSignWindow(c.Scheduler.DefaultSignWindow)
+	return window
+}
+
+// Location is the time zone of job schedules, sign-in windows and sign dates, the local one when
+// scheduler.timezone is empty
+func (c *BotConfig) Location() *time.Location {
+	if c.Scheduler.Timezone == "" {
+		return time.Local
+	}
+	// checked by Validate
+	loc, err := time.LoadLocation(c.Scheduler.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// JobSpec returns the cron expression configured for a job in scheduler.jobs, or fallback
+func (c *BotConfig) JobSpec(name, fallback string) string {
+	if spec := strings.TrimSpace(c.Scheduler.Jobs[name]); spec != "" {
+		return spec
+	}
+	return fallback
+}
+
 func generateRandomKey() string {
 	key := make([]byte, 32)
 	if _, err := rand.Read(key); err != nil {
@@ -131,14 +208,8 @@ func initializeConfig() error {
 		return fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	if Config.Storage.EncryptionKey == "" || Config.Storage.EncryptionKey == "your-32-byte-encryption-key-here!!" {
-		Config.Storage.EncryptionKey = generateRandomKey()
-		logrus.Warnf("new encryption key generated")
-
-		viper.Set("storage.encryption_key", Config.Storage.EncryptionKey)
-		if err := viper.WriteConfig(); err != nil {
-			logrus.Warnf("failed to write new key to config file: %v", err)
-		}
+	if err := resolveSecrets(&Config); err != nil {
+		return err
 	}
 
 	dbPath, err := initDatabasePath(Config.Storage.DBPath)
@@ -150,11 +221,16 @@ func initializeConfig() error {
 	return nil
 }
 
-func InitConfig() error {
+// LoadConfig reads the config file and sets up logging, without touching the database
+func LoadConfig() error {
 	if err := initializeConfig(); err != nil {
 		return err
 	}
 
+	if err := Config.Validate(); err != nil {
+		return err
+	}
+
 	logLevel := Config.Bot.LogLevel
 	if logLevel == "" {
 		logLevel = "info"
@@ -169,28 +245,59 @@ func InitConfig() error {
 		logrus.Debugf("log level set to: %s", logLevel)
 	}
 
-	var dbErr error
-	DB, dbErr = sqlx.Open("sqlite", Config.Storage.DBPath)
-	if dbErr != nil {
-		return fmt.Errorf("failed to connect to database: %w", dbErr)
+	return nil
+}
+
+// OpenStorage opens the database without applying migrations
+func OpenStorage() error {
+	if err := storage.OpenDB(Config.Storage.DBPath); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
 	}
+	DB = storage.GetDB()
 
-	DB.SetMaxOpenConns(1)
-	DB.SetMaxIdleConns(1)
+	return nil
+}
 
-	if err := DB.Ping(); err != nil {
-		return fmt.Errorf("failed to test database connection: %w", err)
+func InitConfig() error {
+	if err := LoadConfig(); err != nil {
+		return err
 	}
 
 	if err := storage.InitDB(Config.Storage.DBPath); err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
+	DB = storage.GetDB()
+
+	if err := resolveEncryptionKey(); err != nil {
+		return err
+	}
 
 	UserRepo = repository.NewUserRepository(DB)
 
 	NotifyRepo = repository.NewNotifyRepository(DB)
 
-	TokenCrypto, err = utils.NewTokenCrypto(Config.Storage.EncryptionKey)
+	SignRepo = repository.NewSignRepository(DB)
+	SignRepo.SetLocation(Config.Location())
+
+	LuckRepo = repository.NewLuckRepository(DB)
+
+	PluginRepo = repository.NewPluginRepository(DB)
+
+	JobRepo = repository.NewJobRepository(DB)
+
+	GithubRepo = repository.NewGithubSubscriptionRepository(DB)
+
+	GithubCursorRepo = repository.NewGithubCursorRepository(DB)
+
+	GithubWatchRepo = repository.NewGithubWatchRepository(DB)
+
+	GithubDigestRepo = repository.NewGithubDigestRepository(DB)
+
+	var err error
+	TokenCrypto, err = utils.NewTokenCrypto(utils.EncryptionKey{
+		ID:  Config.Storage.EncryptionKeyID,
+		Key: Config.Storage.EncryptionKey,
+	}, Config.Storage.OldEncryptionKeys...)
 	if err != nil {
 		return fmt.Errorf("failed to initialize encryption tool: %w", err)
 	}
@@ -0,0 +1,228 @@
+package bot
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+// editors usually write a file in several steps, changes within this window are reloaded once
+const reloadDebounce = time.Second
+
+// restartPaths only take effect after a restart, a change to them is reported but the published
+// config keeps the running values until then
+var restartPaths = []string{
+	"connection",
+	"bot.self_id",
+	"bot.command_prefix",
+	"bot.nicknames",
+	"bot.super_users",
+	"bot.debug",
+	"storage",
+	"github.enabled",
+	"github.token",
+	"github.webhook",
+	"github.unfurl.enabled",
+}
+
+var (
+	current atomic.Pointer[BotConfig]
+
+	reloadMu       sync.Mutex
+	reloadTimer    *time.Timer
+	reloadHandlers []func(old, new *BotConfig)
+)
+
+// Current returns the latest valid config, handlers should read reloadable options through it instead of Config
+func Current() *BotConfig {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return &Config
+}
+
+// OnReload registers fn to be called with the previous and the new config after every successful reload
+func OnReload(fn func(old, new *BotConfig)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	reloadHandlers = append(reloadHandlers, fn)
+}
+
+// WatchConfig reloads the config file whenever it changes
+func WatchConfig() {
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+
+		if reloadTimer != nil {
+			reloadTimer.Stop()
+		}
+		reloadTimer = time.AfterFunc(reloadDebounce, ReloadConfig)
+	})
+	viper.WatchConfig()
+
+	logrus.Infof("watching %s for changes", viper.ConfigFileUsed())
+}
+
+// ReloadConfig reads the config file again and swaps it in if it is valid, otherwise the old config is kept.
+// Either way super users are told what happened.
+func ReloadConfig() {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	old := Current()
+
+	cfg, err := readConfig(old)
+	if err != nil {
+		logrus.WithError(err).Error("config reload rejected, keeping the previous config")
+		announceToSuperUsers(old, fmt.Sprintf("配置文件重新加载失败，继续使用旧配置：\n\n%v", err))
+		return
+	}
+
+	changes := diffConfig(reflect.ValueOf(*old), reflect.ValueOf(*cfg), "")
+	if len(changes) == 0 {
+		logrus.Debug("config file changed but no option differs")
+		return
+	}
+
+	// handlers must not pick up options the running bot cannot switch to
+	keepRestartOptions(reflect.ValueOf(old).Elem(), reflect.ValueOf(cfg).Elem(), "")
+
+	if level, err := logrus.ParseLevel(cfg.Bot.LogLevel); err == nil {
+		logrus.SetLevel(level)
+	}
+
+	current.Store(cfg)
+	for _, fn := range reloadHandlers {
+		fn(old, cfg)
+	}
+
+	logrus.WithField("changes", strings.Join(changes, ", ")).Info("config reloaded")
+
+	lines := make([]string, len(changes))
+	for i, path := range changes {
+		lines[i] = "- " + path
+		if needsRestart(path) {
+			lines[i] += "（需重启生效）"
+		}
+	}
+	announceToSuperUsers(cfg, "配置文件已重新加载，变更项：\n"+strings.Join(lines, "\n"))
+}
+
+// readConfig parses the config file into a new BotConfig with the same post-processing as at startup
+func readConfig(old *BotConfig) (*BotConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(viper.ConfigFileUsed())
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg BotConfig
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if err := resolveSecrets(&cfg); err != nil {
+		return nil, err
+	}
+
+	// a generated key is not part of the config file
+	if cfg.Storage.EncryptionKey == "" || cfg.Storage.EncryptionKey == placeholderEncryptionKey {
+		cfg.Storage.EncryptionKey = old.Storage.EncryptionKey
+	}
+
+	dbPath, err := initDatabasePath(cfg.Storage.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database path: %w", err)
+	}
+	cfg.Storage.DBPath = dbPath
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// diffConfig lists the mapstructure paths of leaf options that differ, slices are compared as a whole
+func diffConfig(old, new reflect.Value, prefix string) []string {
+	var changes []string
+
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		o, n := old.Field(i), new.Field(i)
+		if o.Kind() == reflect.Struct {
+			changes = append(changes, diffConfig(o, n, path)...)
+			continue
+		}
+		if !reflect.DeepEqual(o.Interface(), n.Interface()) {
+			changes = append(changes, path)
+		}
+	}
+
+	return changes
+}
+
+// keepRestartOptions copies the options under restartPaths from old into new
+func keepRestartOptions(old, new reflect.Value, prefix string) {
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		switch {
+		case needsRestart(path):
+			new.Field(i).Set(old.Field(i))
+		case old.Field(i).Kind() == reflect.Struct:
+			keepRestartOptions(old.Field(i), new.Field(i), path)
+		}
+	}
+}
+
+func needsRestart(path string) bool {
+	for _, p := range restartPaths {
+		if path == p || strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func announceToSuperUsers(cfg *BotConfig, msg string) {
+	ctx := zero.GetBot(cfg.Bot.SelfID)
+	if ctx == nil {
+		return
+	}
+
+	for _, id := range cfg.Bot.SuperUsers {
+		ctx.SendPrivateMessage(id, message.Text(msg))
+	}
+}
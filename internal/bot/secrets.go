@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// secrets can be overridden by PAKUCHI_<NAME>, or read from the file named by PAKUCHI_<NAME>_FILE (e.g. Docker secrets)
+const secretEnvPrefix = "PAKUCHI_"
+
+const placeholderEncryptionKey = "your-32-byte-encryption-key-here!!"
+
+// generatedKeyFile stores a generated encryption key next to the database instead of rewriting config.yaml
+const generatedKeyFile = "encryption.key"
+
+type secretSource struct {
+	name  string  // environment variable suffix
+	value *string // config value, overwritten by the resolved secret
+	file  *string // config *_file path
+}
+
+// resolveSecrets applies secrets from the environment and files, in the order
+// PAKUCHI_<NAME>, PAKUCHI_<NAME>_FILE, the *_file config option, then the plain config value
+func resolveSecrets(cfg *BotConfig) error {
+	sources := []secretSource{
+		{name: "ENCRYPTION_KEY", value: &cfg.Storage.EncryptionKey, file: &cfg.Storage.EncryptionKeyFile},
+		{name: "ACCESS_TOKEN", value: &cfg.Connection.AccessToken, file: &cfg.Connection.AccessTokenFile},
+		{name: "GITHUB_TOKEN", value: &cfg.GitHub.Token, file: &cfg.GitHub.TokenFile},
+		{name: "GITHUB_WEBHOOK_SECRET", value: &cfg.GitHub.Webhook.Secret, file: &cfg.GitHub.Webhook.SecretFile},
+		{name: "LLM_API_KEY", value: &cfg.HumanLike.LLM.APIKey, file: &cfg.HumanLike.LLM.APIKeyFile},
+		{name: "VISION_API_KEY", value: &cfg.HumanLike.Vision.APIKey, file: &cfg.HumanLike.Vision.APIKeyFile},
+	}
+
+	for _, source := range sources {
+		env := secretEnvPrefix + source.name
+		if value := os.Getenv(env); value != "" {
+			*source.value = value
+			continue
+		}
+
+		path := os.Getenv(env + "_FILE")
+		if path == "" {
+			path = *source.file
+		}
+		if path == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read secret %s from %s: %w", strings.ToLower(source.name), path, err)
+		}
+		*source.value = strings.TrimSpace(string(data))
+	}
+
+	return nil
+}
+
+// resolveEncryptionKey falls back to a generated key file when no key is configured,
+// and refuses to generate a new key while tokens encrypted with an unknown key exist
+func resolveEncryptionKey() error {
+	key := Config.Storage.EncryptionKey
+	if key != "" && key != placeholderEncryptionKey {
+		return nil
+	}
+
+	keyPath := filepath.Join(filepath.Dir(Config.Storage.DBPath), generatedKeyFile)
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		Config.Storage.EncryptionKey = strings.TrimSpace(string(data))
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read generated encryption key %s: %w", keyPath, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var count int
+	if err := DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM mgclub_users WHERE token != ''`); err != nil {
+		return fmt.Errorf("failed to count encrypted tokens: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("encryption key is not configured but %d encrypted tokens exist, "+
+			"set storage.encryption_key, storage.encryption_key_file, %sENCRYPTION_KEY or %sENCRYPTION_KEY_FILE to the key they were encrypted with",
+			count, secretEnvPrefix, secretEnvPrefix)
+	}
+
+	key = generateRandomKey()
+	file, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save generated encryption key to %s: %w", keyPath, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(key + "\n"); err != nil {
+		return fmt.Errorf("failed to save generated encryption key to %s: %w", keyPath, err)
+	}
+
+	logrus.Warnf("new encryption key generated and saved to %s, back it up together with the database", keyPath)
+	Config.Storage.EncryptionKey = key
+
+	return nil
+}
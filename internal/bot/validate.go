@@ -0,0 +1,302 @@
+package bot
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"PakuchiBot/internal/scheduler"
+	"PakuchiBot/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// accepted values, mirrored from the handlers that consume them
+var (
+	validMonitorTypes = []string{"commit", "release", "issue", "pr", "workflow"}
+	validSources      = []string{"github", "gitea", "gitlab"}
+	validTargetTypes  = []string{"group", "private"}
+	validFormats      = []string{"text", "image"}
+	validDigestModes  = []string{"off", "daily", "weekly"}
+	validJobNames     = []string{"mgclub_sign", "github_poll", "github_digest", "db_cleanup"}
+)
+
+// ConfigError is a single problem in the config file, Path is the YAML path of the offending option
+type ConfigError struct {
+	Path    string
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ConfigErrors collects every problem found by Validate
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = "  " + err.Error()
+	}
+	return fmt.Sprintf("invalid config, %d problems:\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+type configValidator struct {
+	errs ConfigErrors
+}
+
+func (v *configValidator) addf(path string, format string, args ...any) {
+	v.errs = append(v.errs, ConfigError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *configValidator) required(path string, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf(path, "is required")
+	}
+}
+
+func (v *configValidator) oneOf(path string, value string, allowed []string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf(path, "unknown value %q, expected one of %s", value, strings.Join(allowed, ", "))
+}
+
+// oneOfFold is oneOf for values the bot matches without regard to case
+func (v *configValidator) oneOfFold(path string, value string, allowed []string) {
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return
+		}
+	}
+	v.addf(path, "unknown value %q, expected one of %s", value, strings.Join(allowed, ", "))
+}
+
+func (v *configValidator) url(path string, value string, schemes ...string) {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		v.addf(path, "invalid URL %q", value)
+		return
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return
+		}
+	}
+	v.addf(path, "URL scheme must be one of %s, got %q", strings.Join(schemes, ", "), u.Scheme)
+}
+
+// Validate checks the whole config and reports every problem at once, nil when the config is usable
+func (c *BotConfig) Validate() error {
+	v := &configValidator{}
+
+	if c.Connection.WSAddress == "" {
+		v.addf("connection.ws_address", "is required")
+	} else {
+		v.url("connection.ws_address", c.Connection.WSAddress, "ws", "wss")
+	}
+
+	if c.Bot.SelfID <= 0 {
+		v.addf("bot.self_id", "must be the QQ number of the bot")
+	}
+	if c.Bot.LogLevel != "" {
+		if _, err := logrus.ParseLevel(c.Bot.LogLevel); err != nil {
+			v.addf("bot.log_level", "unknown level %q", c.Bot.LogLevel)
+		}
+	}
+	for i, id := range c.Bot.SuperUsers {
+		if id <= 0 {
+			v.addf(fmt.Sprintf("bot.super_users[%d]", i), "must be a QQ number")
+		}
+	}
+
+	c.validateStorage(v)
+
+	if c.Scheduler.CheckInterval <= 0 {
+		v.addf("scheduler.check_interval", "must be a positive number of seconds")
+	}
+	if c.Scheduler.MaxRetries <= 0 {
+		v.addf("scheduler.max_retries", "must be at least 1")
+	}
+	if c.Scheduler.SignConcurrency < 0 {
+		v.addf("scheduler.sign_concurrency", "must not be negative")
+	}
+	if c.Scheduler.SignRateLimit < 0 {
+		v.addf("scheduler.sign_rate_limit", "must not be negative")
+	}
+	if _, err := scheduler.ParseSignWindow(c.Scheduler.DefaultSignWindow); err != nil {
+		v.addf("scheduler.default_sign_window", "must be HH:MM-HH:MM with the end after the start, or empty")
+	}
+	c.validateJobs(v)
+
+	if c.GitHub.Enabled {
+		c.validateGitHub(v)
+	}
+
+	if c.HumanLike.Enabled {
+		c.validateHumanLike(v)
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+func (c *BotConfig) validateStorage(v *configValidator) {
+	s := c.Storage
+
+	v.required("storage.db_path", s.DBPath)
+
+	// an empty key falls back to the generated key file
+	if s.EncryptionKey != "" && s.EncryptionKey != placeholderEncryptionKey && len(s.EncryptionKey) != 32 {
+		v.addf("storage.encryption_key", "must be 32 bytes, got %d", len(s.EncryptionKey))
+	}
+	if s.EncryptionKeyID != "" && !utils.ValidKeyID(s.EncryptionKeyID) {
+		v.addf("storage.encryption_key_id", "may only contain letters, digits, '-' and '_', at most 32 characters")
+	}
+
+	currentID := s.EncryptionKeyID
+	if currentID == "" {
+		currentID = utils.DefaultKeyID
+	}
+	seen := map[string]bool{currentID: true}
+	for i, key := range s.OldEncryptionKeys {
+		path := fmt.Sprintf("storage.old_encryption_keys[%d]", i)
+
+		id := key.ID
+		if id == "" {
+			id = utils.DefaultKeyID
+		}
+		if !utils.ValidKeyID(id) {
+			v.addf(path+".id", "may only contain letters, digits, '-' and '_', at most 32 characters")
+		} else if seen[id] {
+			v.addf(path+".id", "duplicate key id %q", id)
+		}
+		seen[id] = true
+
+		if len(key.Key) != 32 {
+			v.addf(path+".key", "must be 32 bytes, got %d", len(key.Key))
+		}
+	}
+}
+
+func (c *BotConfig) validateGitHub(v *configValidator) {
+	g := c.GitHub
+
+	if g.Interval <= 0 {
+		v.addf("github.interval", "must be a positive number of minutes")
+	}
+
+	for i, repo := range g.Repositories {
+		path := fmt.Sprintf("github.repositories[%d]", i)
+
+		v.required(path+".owner", repo.Owner)
+		v.required(path+".name", repo.Name)
+
+		if len(repo.MonitorType) == 0 {
+			v.addf(path+".monitor_type", "is required")
+		}
+		for j, t := range repo.MonitorType {
+			v.oneOfFold(fmt.Sprintf("%s.monitor_type[%d]", path, j), t, validMonitorTypes)
+		}
+
+		if repo.Source != "" {
+			v.oneOfFold(path+".source", repo.Source, validSources)
+		}
+		if source := strings.ToLower(repo.Source); source == "gitea" || source == "gitlab" {
+			v.required(path+".base_url", repo.BaseURL)
+		}
+		if repo.BaseURL != "" {
+			v.url(path+".base_url", repo.BaseURL, "http", "https")
+		}
+	}
+
+	for i, target := range g.NotifyTargets {
+		path := fmt.Sprintf("github.notify_targets[%d]", i)
+
+		v.oneOf(path+".type", target.Type, validTargetTypes)
+		if target.ID <= 0 {
+			v.addf(path+".id", "must be a group or QQ number")
+		}
+		if target.Format != "" {
+			v.oneOf(path+".format", target.Format, validFormats)
+		}
+		if target.Digest != "" {
+			v.oneOf(path+".digest", target.Digest, validDigestModes)
+		}
+		for j, event := range target.Events {
+			v.oneOfFold(fmt.Sprintf("%s.events[%d]", path, j), event, validMonitorTypes)
+		}
+		for j, repo := range target.Repos {
+			if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" {
+				v.addf(fmt.Sprintf("%s.repos[%d]", path, j), "must be owner/name, got %q", repo)
+			}
+		}
+	}
+
+	if g.Webhook.Enabled {
+		v.required("github.webhook.listen", g.Webhook.Listen)
+		if g.Webhook.Path != "" && !strings.HasPrefix(g.Webhook.Path, "/") {
+			v.addf("github.webhook.path", "must start with '/'")
+		}
+	}
+
+	if g.DigestTime != "" {
+		if _, err := time.Parse("15:04", g.DigestTime); err != nil {
+			v.addf("github.digest_time", "must be HH:MM, got %q", g.DigestTime)
+		}
+	}
+}
+
+func (c *BotConfig) validateJobs(v *configValidator) {
+	loc := time.Local
+	if c.Scheduler.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(c.Scheduler.Timezone); err != nil {
+			v.addf("scheduler.timezone", "unknown time zone %q", c.Scheduler.Timezone)
+			loc = time.Local
+		}
+	}
+
+	names := make([]string, 0, len(c.Scheduler.Jobs))
+	for name := range c.Scheduler.Jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := "scheduler.jobs." + name
+		if !slices.Contains(validJobNames, name) {
+			v.addf(path, "unknown job, expected one of %s", strings.Join(validJobNames, ", "))
+			continue
+		}
+		if _, err := scheduler.ParseSpec(c.Scheduler.Jobs[name], loc); err != nil {
+			v.addf(path, "%v", err)
+		}
+	}
+}
+
+func (c *BotConfig) validateHumanLike(v *configValidator) {
+	h := c.HumanLike
+
+	v.required("humanlike.llm.api_key", h.LLM.APIKey)
+	v.required("humanlike.llm.model", h.LLM.Model)
+	if h.LLM.BaseURL == "" {
+		v.addf("humanlike.llm.base_url", "is required")
+	} else {
+		v.url("humanlike.llm.base_url", h.LLM.BaseURL, "http", "https")
+	}
+	if h.LLM.Temperature < 0 || h.LLM.Temperature > 2 {
+		v.addf("humanlike.llm.temperature", "must be between 0 and 2")
+	}
+	if h.LLM.MaxTokens <= 0 {
+		v.addf("humanlike.llm.max_tokens", "must be positive")
+	}
+}
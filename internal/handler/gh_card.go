@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"PakuchiBot/internal/source"
+	"PakuchiBot/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+const (
+	notifyFormatText  = "text"
+	notifyFormatImage = "image"
+)
+
+func releaseCard(repoPath string, release source.Release) *utils.GithubCard {
+	return &utils.GithubCard{
+		Kind:      "Release " + release.TagName,
+		Repo:      repoPath,
+		Title:     release.Title(),
+		Author:    release.Author,
+		AvatarURL: release.AvatarURL,
+		State:     "released",
+		Time:      release.CreatedAt.Format("2006-01-02 15:04"),
+		Body:      release.Body,
+		URL:       release.URL,
+	}
+}
+
+func issueCard(repoPath string, issue source.Issue) *utils.GithubCard {
+	return &utils.GithubCard{
+		Kind:      fmt.Sprintf("Issue #%d", issue.Number),
+		Repo:      repoPath,
+		Title:     issue.Title,
+		Author:    issue.Author,
+		AvatarURL: issue.AvatarURL,
+		State:     issue.State,
+		Time:      issue.UpdatedAt.Format("2006-01-02 15:04"),
+		Labels:    cardLabels(issue.Labels),
+		Body:      issue.Body,
+		URL:       issue.URL,
+	}
+}
+
+func pullRequestCard(repoPath string, pr source.PullRequest) *utils.GithubCard {
+	state := pr.State
+	if pr.Merged() {
+		state = "merged"
+	}
+
+	return &utils.GithubCard{
+		Kind:         fmt.Sprintf("Pull Request #%d", pr.Number),
+		Repo:         repoPath,
+		Title:        pr.Title,
+		Author:       pr.Author,
+		AvatarURL:    pr.AvatarURL,
+		State:        state,
+		Time:         pr.UpdatedAt.Format("2006-01-02 15:04"),
+		Labels:       cardLabels(pr.Labels),
+		Body:         pr.Body,
+		URL:          pr.URL,
+		HasDiffStat:  pr.HasDiffStat,
+		Additions:    pr.Additions,
+		Deletions:    pr.Deletions,
+		ChangedFiles: pr.ChangedFiles,
+	}
+}
+
+func cardLabels(labels []source.Label) []utils.GithubCardLabel {
+	result := make([]utils.GithubCardLabel, 0, len(labels))
+	for _, label := range labels {
+		color := label.Color
+		if color == "" {
+			// GitLab only lists label names
+			color = "ededed"
+		}
+		result = append(result, utils.GithubCardLabel{
+			Name:  label.Name,
+			Color: strings.TrimPrefix(color, "#"),
+		})
+	}
+	return result
+}
+
+func (g *GithubNotifier) hasImageTargets() bool {
+	for _, target := range g.loadNotifyTargets() {
+		if strings.EqualFold(target.Format, notifyFormatImage) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GithubNotifier) sendToTargets(messageContent string, event NotifyEvent, card *utils.GithubCard) {
+	var cardImage []byte
+	cardRendered := false
+
+	for _, target := range g.loadNotifyTargets() {
+		// digest targets only receive the periodic summary
+		if isDigestMode(target.Digest) || !target.allows(event) {
+			continue
+		}
+
+		msg := message.Message{message.Text(messageContent)}
+		if card != nil && strings.EqualFold(target.Format, notifyFormatImage) {
+			// render lazily and only once for all image targets
+			if !cardRendered {
+				cardRendered = true
+				image, err := utils.GenerateGithubCard(*card)
+				if err != nil {
+					logrus.WithFields(logrus.Fields{
+						"repo":  event.Repo,
+						"type":  event.Type,
+						"error": err,
+					}).Error("failed to generate github card")
+				}
+				cardImage = image
+			}
+			if cardImage != nil {
+				msg = message.Message{
+					message.ImageBytes(cardImage),
+					message.Text("详情：" + card.URL),
+				}
+			}
+		}
+
+		switch strings.ToLower(target.Type) {
+		case "group":
+			g.bot.SendGroupMessage(target.ID, msg)
+		case "private":
+			g.bot.SendPrivateMessage(target.ID, msg)
+		default:
+			logrus.WithFields(logrus.Fields{
+				"target_type": target.Type,
+				"target_id":   target.ID,
+			}).Warn("unknown notify target type")
+		}
+	}
+}
+
+func (g *GithubNotifier) handleFormatCommand(ctx *zero.Ctx, args []string) {
+	if !g.config().Enabled {
+		ctx.Send("GitHub 通知功能未启用")
+		return
+	}
+
+	if len(args) < 1 || (args[0] != notifyFormatText && args[0] != notifyFormatImage) {
+		ctx.Send("请指定通知格式，例如: /github format image\n支持的格式：text, image")
+		return
+	}
+
+	targetType, targetID := getEventTarget(ctx)
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := g.subRepo.UpsertTargetFormat(reqCtx, targetType, targetID, args[0]); err != nil {
+		ctx.Send(fmt.Sprintf("保存通知格式时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	if args[0] == notifyFormatImage {
+		ctx.Send("已切换为图片卡片通知，Release、Issue 和 PR 将以图片形式推送")
+	} else {
+		ctx.Send("已切换为文字通知")
+	}
+}
@@ -0,0 +1,295 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"PakuchiBot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+const (
+	digestModeOff    = "off"
+	digestModeDaily  = "daily"
+	digestModeWeekly = "weekly"
+
+	defaultDigestTime = "09:00"
+
+	// entries listed per section before collapsing the rest into a count
+	maxDigestItems = 5
+)
+
+func isDigestMode(mode string) bool {
+	return mode == digestModeDaily || mode == digestModeWeekly
+}
+
+// recordEvent keeps a delivered event so digest targets can be summarized later
+func (g *GithubNotifier) recordEvent(event NotifyEvent) {
+	if g.digestRepo == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	occurredAt := event.Time
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	err := g.digestRepo.RecordEvent(ctx, &repository.GithubEvent{
+		Repo:       event.Repo,
+		EventType:  event.Type,
+		Action:     event.Action,
+		Branch:     event.Branch,
+		Labels:     strings.Join(event.Labels, ","),
+		Title:      event.Title,
+		Author:     event.Author,
+		URL:        event.URL,
+		OccurredAt: occurredAt,
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"repo":  event.Repo,
+			"type":  event.Type,
+			"error": err,
+		}).Error("failed to record github event")
+	}
+}
+
+// digestSchedule returns the most recent scheduled digest time at or before now
+func (g *GithubNotifier) digestSchedule(mode string, now time.Time) time.Time {
+	digestTime := g.config().DigestTime
+	if digestTime == "" {
+		digestTime = defaultDigestTime
+	}
+	clock, err := time.Parse("15:04", digestTime)
+	if err != nil {
+		clock, _ = time.Parse("15:04", defaultDigestTime)
+	}
+
+	scheduled := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if scheduled.After(now) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+
+	// weekly digests go out on Mondays
+	if mode == digestModeWeekly {
+		offset := (int(scheduled.Weekday()) + 6) % 7
+		scheduled = scheduled.AddDate(0, 0, -offset)
+	}
+
+	return scheduled
+}
+
+func (g *GithubNotifier) sendDueDigests(now time.Time) {
+	if g.digestRepo == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	digests, err := g.digestRepo.GetAll(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("failed to load github digests")
+		return
+	}
+
+	lastSent := make(map[string]*time.Time, len(digests))
+	for _, digest := range digests {
+		lastSent[fmt.Sprintf("%s:%d", digest.TargetType, digest.TargetID)] = digest.LastSentAt
+	}
+
+	for _, target := range g.loadNotifyTargets() {
+		if !isDigestMode(target.Digest) {
+			continue
+		}
+
+		scheduled := g.digestSchedule(target.Digest, now)
+		sentAt := lastSent[fmt.Sprintf("%s:%d", target.Type, target.ID)]
+
+		// a target that just switched to digest mode starts collecting from now instead of replaying history
+		if sentAt == nil {
+			if err := g.digestRepo.MarkSent(ctx, target.Type, target.ID, now); err != nil {
+				logrus.WithError(err).Error("failed to initialize github digest")
+			}
+			continue
+		}
+		if !sentAt.Before(scheduled) {
+			continue
+		}
+
+		events, err := g.digestRepo.ListEventsBetween(ctx, *sentAt, scheduled)
+		if err != nil {
+			logrus.WithError(err).Error("failed to load github events for digest")
+			continue
+		}
+
+		// mark first so a failed send is not retried every minute
+		if err := g.digestRepo.MarkSent(ctx, target.Type, target.ID, scheduled); err != nil {
+			logrus.WithError(err).Error("failed to mark github digest sent")
+			continue
+		}
+
+		content := formatDigestMessage(target, events, *sentAt, scheduled)
+		if content == "" {
+			continue
+		}
+
+		switch strings.ToLower(target.Type) {
+		case "group":
+			g.bot.SendGroupMessage(target.ID, message.Text(content))
+		case "private":
+			g.bot.SendPrivateMessage(target.ID, message.Text(content))
+		default:
+			logrus.WithFields(logrus.Fields{
+				"target_type": target.Type,
+				"target_id":   target.ID,
+			}).Warn("unknown notify target type")
+		}
+	}
+}
+
+type repoDigest struct {
+	commits      []repository.GithubEvent
+	authors      []string
+	releases     []repository.GithubEvent
+	openedIssues []repository.GithubEvent
+	closedIssues []repository.GithubEvent
+	mergedPRs    []repository.GithubEvent
+}
+
+func (d *repoDigest) isEmpty() bool {
+	return len(d.commits) == 0 && len(d.releases) == 0 && len(d.openedIssues) == 0 &&
+		len(d.closedIssues) == 0 && len(d.mergedPRs) == 0
+}
+
+// formatDigestMessage summarizes the events a target would have received, empty when there is nothing to report
+func formatDigestMessage(target NotifyTarget, events []repository.GithubEvent, from, to time.Time) string {
+	repos := make([]string, 0)
+	digests := make(map[string]*repoDigest)
+
+	for _, event := range events {
+		notifyEvent := NotifyEvent{
+			Repo:   event.Repo,
+			Type:   event.EventType,
+			Branch: event.Branch,
+			Labels: splitList(event.Labels),
+		}
+		if !target.allows(notifyEvent) {
+			continue
+		}
+
+		digest, ok := digests[event.Repo]
+		if !ok {
+			digest = &repoDigest{}
+			digests[event.Repo] = digest
+			repos = append(repos, event.Repo)
+		}
+
+		switch {
+		case event.EventType == "commit":
+			digest.commits = append(digest.commits, event)
+			if event.Author != "" && !containsFold(digest.authors, event.Author) {
+				digest.authors = append(digest.authors, event.Author)
+			}
+		case event.EventType == "release":
+			digest.releases = append(digest.releases, event)
+		case event.EventType == "issue" && event.Action == "opened":
+			digest.openedIssues = append(digest.openedIssues, event)
+		case event.EventType == "issue" && event.Action == "closed":
+			digest.closedIssues = append(digest.closedIssues, event)
+		case event.EventType == "pr" && event.Action == "merged":
+			digest.mergedPRs = append(digest.mergedPRs, event)
+		}
+	}
+
+	title := "📰 GitHub 每日摘要"
+	if target.Digest == digestModeWeekly {
+		title = "📰 GitHub 每周摘要"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s\n时间：%s ~ %s\n", title, from.Format("01-02 15:04"), to.Format("01-02 15:04")))
+
+	hasContent := false
+	for _, repo := range repos {
+		digest := digests[repo]
+		if digest.isEmpty() {
+			continue
+		}
+		hasContent = true
+
+		sb.WriteString(fmt.Sprintf("\n【%s】\n", repo))
+		if len(digest.commits) > 0 {
+			sb.WriteString(fmt.Sprintf("🔄 %d 个提交，贡献者：%s\n", len(digest.commits), strings.Join(digest.authors, ", ")))
+		}
+		writeDigestSection(&sb, "🚀 新版本", digest.releases)
+		writeDigestSection(&sb, "📝 新建 Issue", digest.openedIssues)
+		writeDigestSection(&sb, "✅ 关闭 Issue", digest.closedIssues)
+		writeDigestSection(&sb, "🔀 合并 PR", digest.mergedPRs)
+	}
+
+	if !hasContent {
+		return ""
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func writeDigestSection(sb *strings.Builder, title string, events []repository.GithubEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	sb.WriteString(fmt.Sprintf("%s %d 个\n", title, len(events)))
+	for i, event := range events {
+		if i == maxDigestItems {
+			sb.WriteString(fmt.Sprintf("  …… 以及另外 %d 个\n", len(events)-maxDigestItems))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("  · %s\n", event.Title))
+	}
+}
+
+func (g *GithubNotifier) handleDigestCommand(ctx *zero.Ctx, args []string) {
+	if !g.config().Enabled {
+		ctx.Send("GitHub 通知功能未启用")
+		return
+	}
+
+	if len(args) < 1 || (args[0] != digestModeOff && !isDigestMode(args[0])) {
+		ctx.Send("请指定摘要模式，例如: /github digest daily\n支持的模式：daily, weekly, off（off 表示实时推送）")
+		return
+	}
+
+	targetType, targetID := getEventTarget(ctx)
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := g.digestRepo.UpsertMode(reqCtx, targetType, targetID, args[0]); err != nil {
+		ctx.Send(fmt.Sprintf("保存摘要模式时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	digestTime := g.config().DigestTime
+	if digestTime == "" {
+		digestTime = defaultDigestTime
+	}
+
+	switch args[0] {
+	case digestModeDaily:
+		ctx.Send(fmt.Sprintf("已切换为每日摘要，每天 %s 推送一次汇总，不再实时推送", digestTime))
+	case digestModeWeekly:
+		ctx.Send(fmt.Sprintf("已切换为每周摘要，每周一 %s 推送一次汇总，不再实时推送", digestTime))
+	default:
+		ctx.Send("已关闭摘要模式，恢复实时推送")
+	}
+}
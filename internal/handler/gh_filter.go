@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// NotifyEvent describes a GitHub event for matching against target filters
+type NotifyEvent struct {
+	Repo   string   // owner/name
+	Type   string   // commit, release, issue, pr, workflow
+	Branch string   // commits and workflow runs only
+	Labels []string // issues and pull requests only
+
+	// recorded for digests
+	Action string // pushed, published, opened, closed, reopened, merged, updated
+	Title  string
+	Author string
+	URL    string
+	Time   time.Time
+}
+
+// EventFilter narrows down which events a target receives, empty fields match everything
+type EventFilter struct {
+	Events   []string `mapstructure:"events"`   // monitor types, e.g. release, pr
+	Branches []string `mapstructure:"branches"` // glob patterns, e.g. main, release/*
+	Labels   []string `mapstructure:"labels"`   // issues and pull requests must carry at least one of them
+}
+
+func (f EventFilter) IsEmpty() bool {
+	return len(f.Events) == 0 && len(f.Branches) == 0 && len(f.Labels) == 0
+}
+
+func (f EventFilter) allows(event NotifyEvent) bool {
+	if len(f.Events) > 0 && !containsFold(f.Events, event.Type) {
+		return false
+	}
+
+	if len(f.Branches) > 0 && (event.Type == "commit" || event.Type == "workflow") {
+		matched := false
+		for _, pattern := range f.Branches {
+			if ok, _ := path.Match(pattern, event.Branch); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.Labels) > 0 && (event.Type == "issue" || event.Type == "pr") {
+		matched := false
+		for _, label := range event.Labels {
+			if containsFold(f.Labels, label) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f EventFilter) String() string {
+	parts := make([]string, 0, 3)
+	if len(f.Events) > 0 {
+		parts = append(parts, "事件："+strings.Join(f.Events, ","))
+	}
+	if len(f.Branches) > 0 {
+		parts = append(parts, "分支："+strings.Join(f.Branches, ","))
+	}
+	if len(f.Labels) > 0 {
+		parts = append(parts, "标签："+strings.Join(f.Labels, ","))
+	}
+	return strings.Join(parts, "；")
+}
+
+// parseFilterFlags parses "--events a,b --branches main --labels bug" style arguments
+func parseFilterFlags(args []string) (EventFilter, error) {
+	var filter EventFilter
+
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		if i+1 >= len(args) {
+			return filter, fmt.Errorf("参数 %s 缺少值", flag)
+		}
+		values := splitList(args[i+1])
+		i++
+
+		switch flag {
+		case "--events":
+			for _, v := range values {
+				v = strings.ToLower(v)
+				if !isSupportedMonitorType(v) {
+					return filter, fmt.Errorf("不支持的事件类型 %s，支持的类型：%s", v, strings.Join(supportedMonitorTypes, ", "))
+				}
+				filter.Events = append(filter.Events, v)
+			}
+		case "--branches":
+			for _, v := range values {
+				if _, err := path.Match(v, ""); err != nil {
+					return filter, fmt.Errorf("分支匹配规则 %s 无效", v)
+				}
+			}
+			filter.Branches = append(filter.Branches, values...)
+		case "--labels":
+			filter.Labels = append(filter.Labels, values...)
+		default:
+			return filter, fmt.Errorf("未知参数 %s，支持的参数：--events, --branches, --labels", flag)
+		}
+	}
+
+	return filter, nil
+}
+
+func splitList(s string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
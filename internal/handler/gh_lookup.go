@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"PakuchiBot/internal/source"
+	"PakuchiBot/internal/utils"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/sirupsen/logrus"
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+var (
+	// owner/repo#123
+	issueRefPattern = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)#(\d+)$`)
+	// https://github.com/owner/repo/issues/1, /pull/2 or /commit/<sha>
+	githubURLPattern = regexp.MustCompile(`https?://github\.com/([\w.-]+)/([\w.-]+)/(issues|pull|commit)/([0-9a-fA-F]+|\d+)`)
+)
+
+func parseIssueRef(ref string) (owner, repo string, number int, ok bool) {
+	matches := issueRefPattern.FindStringSubmatch(ref)
+	if matches == nil {
+		return "", "", 0, false
+	}
+	number, err := strconv.Atoi(matches[3])
+	if err != nil {
+		return "", "", 0, false
+	}
+	return matches[1], matches[2], number, true
+}
+
+func (g *GithubNotifier) handleIssueCommand(ctx *zero.Ctx, args []string) {
+	if len(args) < 1 {
+		ctx.Send("请指定 Issue，例如: /github issue owner/repo#123")
+		return
+	}
+	owner, repo, number, ok := parseIssueRef(args[0])
+	if !ok {
+		ctx.Send("格式错误，应为 owner/repo#123")
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	issue, resp, err := g.client.Issues.Get(reqCtx, owner, repo, number)
+	if err != nil {
+		g.sendLookupError(ctx, resp, fmt.Sprintf("Issue %s", args[0]), err)
+		return
+	}
+
+	// the issues endpoint also answers for pull requests
+	if issue.IsPullRequest() {
+		g.replyPullRequest(ctx, reqCtx, owner, repo, number)
+		return
+	}
+
+	repoPath := fmt.Sprintf("%s/%s", owner, repo)
+	g.sendCard(ctx, issueCard(repoPath, source.GithubIssue(issue)), true)
+}
+
+func (g *GithubNotifier) handlePullRequestCommand(ctx *zero.Ctx, args []string) {
+	if len(args) < 1 {
+		ctx.Send("请指定 PR，例如: /github pr owner/repo#45")
+		return
+	}
+	owner, repo, number, ok := parseIssueRef(args[0])
+	if !ok {
+		ctx.Send("格式错误，应为 owner/repo#45")
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	g.replyPullRequest(ctx, reqCtx, owner, repo, number)
+}
+
+func (g *GithubNotifier) replyPullRequest(ctx *zero.Ctx, reqCtx context.Context, owner, repo string, number int) {
+	pr, resp, err := g.client.PullRequests.Get(reqCtx, owner, repo, number)
+	if err != nil {
+		g.sendLookupError(ctx, resp, fmt.Sprintf("PR %s/%s#%d", owner, repo, number), err)
+		return
+	}
+
+	repoPath := fmt.Sprintf("%s/%s", owner, repo)
+	g.sendCard(ctx, pullRequestCard(repoPath, source.GithubPullRequest(pr)), true)
+}
+
+func (g *GithubNotifier) handleReleaseCommand(ctx *zero.Ctx, args []string) {
+	if len(args) < 1 {
+		ctx.Send("请指定仓库，例如: /github release owner/repo [tag]")
+		return
+	}
+	parts := strings.Split(args[0], "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		ctx.Send("仓库格式错误，应为 owner/repo")
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		release *github.RepositoryRelease
+		resp    *github.Response
+		err     error
+		target  string
+	)
+	if len(args) > 1 {
+		target = fmt.Sprintf("仓库 %s 的版本 %s", args[0], args[1])
+		release, resp, err = g.client.Repositories.GetReleaseByTag(reqCtx, parts[0], parts[1], args[1])
+	} else {
+		target = fmt.Sprintf("仓库 %s 的最新版本", args[0])
+		release, resp, err = g.client.Repositories.GetLatestRelease(reqCtx, parts[0], parts[1])
+	}
+	if err != nil {
+		g.sendLookupError(ctx, resp, target, err)
+		return
+	}
+
+	g.sendCard(ctx, releaseCard(args[0], source.GithubRelease(release)), true)
+}
+
+func (g *GithubNotifier) sendLookupError(ctx *zero.Ctx, resp *github.Response, target string, err error) {
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		ctx.Send(fmt.Sprintf("%s 不存在或无权访问", target))
+		return
+	}
+	ctx.Send(fmt.Sprintf("查询 %s 时出错啦，请将错误信息反馈给管理员哦\n\n%v", target, err))
+}
+
+// sendCard replies with the rendered card, falling back to a text summary when rendering fails
+func (g *GithubNotifier) sendCard(ctx *zero.Ctx, card *utils.GithubCard, withLink bool) {
+	image, err := utils.GenerateGithubCard(*card)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"repo":  card.Repo,
+			"kind":  card.Kind,
+			"error": err,
+		}).Error("failed to generate github card")
+
+		ctx.Send(fmt.Sprintf("%s · %s\n%s\n%s  %s\n\n详情：%s",
+			card.Repo, card.Kind, card.Title, card.Author, card.State, card.URL))
+		return
+	}
+
+	msg := message.Message{message.ImageBytes(image)}
+	if withLink {
+		msg = append(msg, message.Text("详情："+card.URL))
+	}
+	ctx.Send(msg)
+}
+
+func (g *GithubNotifier) isUnfurlGroup(groupID int64) bool {
+	if !g.config().Unfurl.Enabled {
+		return false
+	}
+	for _, id := range g.config().Unfurl.Groups {
+		if id == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleUnfurl replies with a summary card for the first github.com issue, pull request or commit URL in a message
+func (g *GithubNotifier) handleUnfurl(ctx *zero.Ctx) {
+	if ctx.Event.GroupID == 0 || !g.isUnfurlGroup(ctx.Event.GroupID) {
+		return
+	}
+
+	matches := githubURLPattern.FindStringSubmatch(ctx.ExtractPlainText())
+	if matches == nil {
+		return
+	}
+	owner, repo, kind, ref := matches[1], matches[2], matches[3], matches[4]
+	repoPath := fmt.Sprintf("%s/%s", owner, repo)
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card *utils.GithubCard
+	switch kind {
+	case "issues", "pull":
+		number, err := strconv.Atoi(ref)
+		if err != nil {
+			return
+		}
+		if kind == "pull" {
+			pr, _, err := g.client.PullRequests.Get(reqCtx, owner, repo, number)
+			if err != nil {
+				return
+			}
+			card = pullRequestCard(repoPath, source.GithubPullRequest(pr))
+		} else {
+			issue, _, err := g.client.Issues.Get(reqCtx, owner, repo, number)
+			if err != nil {
+				return
+			}
+			card = issueCard(repoPath, source.GithubIssue(issue))
+		}
+	case "commit":
+		commit, _, err := g.client.Repositories.GetCommit(reqCtx, owner, repo, ref, nil)
+		if err != nil {
+			return
+		}
+		card = commitCard(repoPath, commit)
+	}
+
+	// the link is already in the chat, the card alone is enough
+	g.sendCard(ctx, card, false)
+}
+
+func commitCard(repoPath string, commit *github.RepositoryCommit) *utils.GithubCard {
+	subject, body, _ := strings.Cut(strings.TrimSpace(commit.GetCommit().GetMessage()), "\n")
+
+	author := commit.GetAuthor().GetLogin()
+	if author == "" {
+		author = commit.GetCommit().GetAuthor().GetName()
+	}
+
+	return &utils.GithubCard{
+		Kind:         fmt.Sprintf("Commit %.7s", commit.GetSHA()),
+		Repo:         repoPath,
+		Title:        subject,
+		Author:       author,
+		AvatarURL:    commit.GetAuthor().GetAvatarURL(),
+		Time:         commit.GetCommit().GetAuthor().GetDate().Format("2006-01-02 15:04"),
+		Body:         strings.TrimSpace(body),
+		URL:          commit.GetHTMLURL(),
+		HasDiffStat:  commit.Stats != nil,
+		Additions:    commit.GetStats().GetAdditions(),
+		Deletions:    commit.GetStats().GetDeletions(),
+		ChangedFiles: len(commit.Files),
+	}
+}
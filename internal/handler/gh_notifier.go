@@ -2,12 +2,20 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"PakuchiBot/internal/bot"
+	"PakuchiBot/internal/repository"
+	"PakuchiBot/internal/scheduler"
+	"PakuchiBot/internal/source"
+	"PakuchiBot/internal/utils"
 
 	"github.com/google/go-github/v45/github"
 	"github.com/sirupsen/logrus"
@@ -15,23 +23,56 @@ import (
 	"github.com/wdvxdr1123/ZeroBot/message"
 )
 
+// commits listed in a batched commit message before collapsing the rest into a count
+const maxShortlogLines = 15
+
+const (
+	jobGithubPoll   = "github_poll"
+	jobGithubDigest = "github_digest"
+
+	// digests are due on a minute boundary, so checking every minute sends them on time
+	defaultDigestSpec = "* * * * *"
+)
+
 type GithubNotifier struct {
-	client       *github.Client
-	bot          *zero.Ctx
+	client     *github.Client
+	bot        *zero.Ctx
+	subRepo    *repository.GithubSubscriptionRepository
+	cursorRepo *repository.GithubCursorRepository
+	watchRepo  *repository.GithubWatchRepository
+	digestRepo *repository.GithubDigestRepository
+	transport  *etagTransport
+
+	mu              sync.Mutex
+	defaultBranches map[string]string
+	sources         map[string]source.Source
+
+	configMu     sync.RWMutex
 	notifyConfig GithubNotifyConfig
-	lastCheck    map[string]time.Time
 }
 
 type RepoConfig struct {
-	Owner       string   `mapstructure:"owner"`
-	Name        string   `mapstructure:"name"`
-	MonitorType []string `mapstructure:"monitor_type"` // commit, release, issue, pr
+	Owner         string   `mapstructure:"owner"`
+	Name          string   `mapstructure:"name"`
+	MonitorType   []string `mapstructure:"monitor_type"`   // commit, release, issue, pr, workflow
+	WebhookSecret string   `mapstructure:"webhook_secret"` // overrides the global webhook secret
+	Source        string   `mapstructure:"source"`         // github, gitea, gitlab; empty means github
+	BaseURL       string   `mapstructure:"base_url"`       // server address for GitHub Enterprise, Gitea and GitLab
+	Token         string   `mapstructure:"token"`          // overrides the global token
+
+	// workflow monitoring
+	Branches        []string `mapstructure:"branches"`         // branches whose runs are reported, empty means the default branch
+	NotifyRecovered bool     `mapstructure:"notify_recovered"` // also report the first successful run after a failure
 }
 
 type NotifyTarget struct {
-	Type  string   `mapstructure:"type"` // group, private
-	ID    int64    `mapstructure:"id"`
-	Repos []string `mapstructure:"repos"` // format: "owner/name", empty means all repositories
+	Type        string   `mapstructure:"type"` // group, private
+	ID          int64    `mapstructure:"id"`
+	Repos       []string `mapstructure:"repos"`  // format: "owner/name", empty means all repositories
+	Format      string   `mapstructure:"format"` // text, image; empty means text
+	Digest      string   `mapstructure:"digest"` // daily, weekly; empty means real-time notifications
+	EventFilter `mapstructure:",squash"`
+	RepoFilters map[string]EventFilter `mapstructure:"-"` // per-subscription filters keyed by "owner/name"
 }
 
 type GithubNotifyConfig struct {
@@ -40,66 +81,219 @@ type GithubNotifyConfig struct {
 	Token         string         `mapstructure:"token"`    // GitHub API Token
 	Repositories  []RepoConfig   `mapstructure:"repositories"`
 	NotifyTargets []NotifyTarget `mapstructure:"notify_targets"`
+	Webhook       WebhookConfig  `mapstructure:"webhook"`
+	DigestTime    string         `mapstructure:"digest_time"` // digest send time, e.g. "09:00"
+	Unfurl        UnfurlConfig   `mapstructure:"unfurl"`
 }
 
-func RegisterGitHubHandler() {
+type UnfurlConfig struct {
+	Enabled bool    `mapstructure:"enabled"`
+	Groups  []int64 `mapstructure:"groups"` // groups where pasted github.com links are expanded
+}
+
+type WebhookConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	Listen         string `mapstructure:"listen"`          // listen address, e.g. ":8080"
+	Path           string `mapstructure:"path"`            // request path, e.g. "/github/webhook"
+	Secret         string `mapstructure:"secret"`          // default secret for repositories without their own
+	DisablePolling bool   `mapstructure:"disable_polling"` // rely on webhook deliveries only
+}
+
+func RegisterGitHubHandler(jobs *scheduler.Scheduler) {
 	if !bot.Config.GitHub.Enabled {
 		return
 	}
 
+	githubHandler := NewGithubNotifier(
+		zero.GetBot(bot.Config.Bot.SelfID),
+		githubNotifyConfigFrom(&bot.Config),
+		bot.GithubRepo,
+		bot.GithubCursorRepo,
+		bot.GithubWatchRepo,
+		bot.GithubDigestRepo,
+	)
+	githubHandler.Register()
+	githubHandler.registerJobs(jobs, &bot.Config)
+	log.Printf("GitHub notifier registered with %d repositories and %d notify targets",
+		len(githubHandler.loadRepositories()), len(githubHandler.loadNotifyTargets()))
+
+	bot.OnReload(func(_, cfg *bot.BotConfig) {
+		githubHandler.applyConfig(githubNotifyConfigFrom(cfg))
+		rescheduleGithubJobs(jobs, cfg)
+	})
+}
+
+// githubPollSpec polls every github.interval minutes unless scheduler.jobs sets an expression
+func githubPollSpec(cfg *bot.BotConfig) string {
+	interval := cfg.GitHub.Interval
+	if interval < 1 {
+		interval = 5
+	}
+	return cfg.JobSpec(jobGithubPoll, fmt.Sprintf("@every %dm", interval))
+}
+
+func githubDigestSpec(cfg *bot.BotConfig) string {
+	return cfg.JobSpec(jobGithubDigest, defaultDigestSpec)
+}
+
+// registerJobs schedules polling and digests, the webhook server and commands don't depend on them
+func (g *GithubNotifier) registerJobs(jobs *scheduler.Scheduler, cfg *bot.BotConfig) {
+	if !g.config().Enabled {
+		return
+	}
+
+	// repositories may be added at runtime via /github watch, so poll even when none are configured yet
+	if !g.config().Webhook.DisablePolling {
+		poll := scheduler.Job{
+			Name:        jobGithubPoll,
+			Description: "GitHub 仓库轮询",
+			Run: func(ctx context.Context) error {
+				g.checkAllRepositories()
+				return nil
+			},
+		}
+		if err := jobs.Register(poll, githubPollSpec(cfg)); err != nil {
+			logrus.WithError(err).Error("failed to schedule github polling")
+		} else {
+			// check once right away instead of waiting for the first interval
+			go jobs.RunNow(jobGithubPoll)
+		}
+	}
+
+	digest := scheduler.Job{
+		Name:        jobGithubDigest,
+		Description: "GitHub 摘要推送",
+		Run: func(ctx context.Context) error {
+			// github.digest_time follows scheduler.timezone
+			g.sendDueDigests(time.Now().In(jobs.Location()))
+			return nil
+		},
+	}
+	if err := jobs.Register(digest, githubDigestSpec(cfg)); err != nil {
+		logrus.WithError(err).Error("failed to schedule github digests")
+	}
+}
+
+func rescheduleGithubJobs(jobs *scheduler.Scheduler, cfg *bot.BotConfig) {
+	specs := map[string]string{
+		jobGithubPoll:   githubPollSpec(cfg),
+		jobGithubDigest: githubDigestSpec(cfg),
+	}
+	for name, spec := range specs {
+		// polling isn't registered when webhook.disable_polling is set
+		if err := jobs.Reschedule(name, spec); err != nil && !errors.Is(err, scheduler.ErrJobNotFound) {
+			logrus.WithField("job", name).WithError(err).Error("failed to reschedule github job")
+		}
+	}
+}
+
+func githubNotifyConfigFrom(cfg *bot.BotConfig) GithubNotifyConfig {
 	githubNotifyConfig := GithubNotifyConfig{
-		Enabled:       bot.Config.GitHub.Enabled,
-		Interval:      bot.Config.GitHub.Interval,
-		Token:         bot.Config.GitHub.Token,
+		Enabled:       cfg.GitHub.Enabled,
+		Interval:      cfg.GitHub.Interval,
+		Token:         cfg.GitHub.Token,
+		DigestTime:    cfg.GitHub.DigestTime,
 		Repositories:  make([]RepoConfig, 0),
 		NotifyTargets: make([]NotifyTarget, 0),
+		Webhook: WebhookConfig{
+			Enabled:        cfg.GitHub.Webhook.Enabled,
+			Listen:         cfg.GitHub.Webhook.Listen,
+			Path:           cfg.GitHub.Webhook.Path,
+			Secret:         cfg.GitHub.Webhook.Secret,
+			DisablePolling: cfg.GitHub.Webhook.DisablePolling,
+		},
+		Unfurl: UnfurlConfig{
+			Enabled: cfg.GitHub.Unfurl.Enabled,
+			Groups:  cfg.GitHub.Unfurl.Groups,
+		},
 	}
 
-	for _, repo := range bot.Config.GitHub.Repositories {
+	for _, repo := range cfg.GitHub.Repositories {
 		githubNotifyConfig.Repositories = append(githubNotifyConfig.Repositories, RepoConfig{
-			Owner:       repo.Owner,
-			Name:        repo.Name,
-			MonitorType: repo.MonitorType,
+			Owner:         repo.Owner,
+			Name:          repo.Name,
+			MonitorType:   repo.MonitorType,
+			WebhookSecret: repo.WebhookSecret,
+			Source:        repo.Source,
+			BaseURL:       repo.BaseURL,
+			Token:         repo.Token,
+
+			Branches:        repo.Branches,
+			NotifyRecovered: repo.NotifyRecovered,
 		})
 	}
 
-	for _, target := range bot.Config.GitHub.NotifyTargets {
+	for _, target := range cfg.GitHub.NotifyTargets {
 		notifyTarget := NotifyTarget{
-			Type:  target.Type,
-			ID:    target.ID,
-			Repos: make([]string, 0),
+			Type:   target.Type,
+			ID:     target.ID,
+			Repos:  append(make([]string, 0), target.Repos...),
+			Format: target.Format,
+			Digest: target.Digest,
+			EventFilter: EventFilter{
+				Events:   target.Events,
+				Branches: target.Branches,
+				Labels:   target.Labels,
+			},
 		}
 		githubNotifyConfig.NotifyTargets = append(githubNotifyConfig.NotifyTargets, notifyTarget)
 	}
 
-	githubHandler := NewGithubNotifier(zero.GetBot(bot.Config.Bot.SelfID), githubNotifyConfig)
-	githubHandler.Register()
-	log.Printf("GitHub notifier registered with %d repositories and %d notify targets",
-		len(githubNotifyConfig.Repositories), len(githubNotifyConfig.NotifyTargets))
+	return githubNotifyConfig
 }
 
-func NewGithubNotifier(bot *zero.Ctx, config GithubNotifyConfig) *GithubNotifier {
+func NewGithubNotifier(
+	bot *zero.Ctx,
+	config GithubNotifyConfig,
+	subRepo *repository.GithubSubscriptionRepository,
+	cursorRepo *repository.GithubCursorRepository,
+	watchRepo *repository.GithubWatchRepository,
+	digestRepo *repository.GithubDigestRepository,
+) *GithubNotifier {
+	transport := newETagTransport(nil)
+
 	var client *github.Client
 	if config.Token != "" {
 		ts := github.BasicAuthTransport{
-			Username: config.Token,
-			Password: "x-oauth-basic",
+			Username:  config.Token,
+			Password:  "x-oauth-basic",
+			Transport: transport,
 		}
 		client = github.NewClient(ts.Client())
 	} else {
-		client = github.NewClient(nil)
+		client = github.NewClient(&http.Client{Transport: transport})
 	}
 
 	return &GithubNotifier{
-		client:       client,
-		bot:          bot,
+		client:     client,
+		bot:        bot,
+		subRepo:    subRepo,
+		cursorRepo: cursorRepo,
+		watchRepo:  watchRepo,
+		digestRepo: digestRepo,
+		transport:  transport,
+
+		defaultBranches: make(map[string]string),
+		sources:         make(map[string]source.Source),
+
 		notifyConfig: config,
-		lastCheck:    make(map[string]time.Time),
 	}
 }
 
+// githubCommandRoles annotates /github subcommands with the role they require, unlisted ones are open to everyone
+var githubCommandRoles = map[string]role{
+	"subscribe":   roleGroupAdmin,
+	"unsubscribe": roleGroupAdmin,
+	"format":      roleGroupAdmin,
+	"digest":      roleGroupAdmin,
+	"watch":       roleSuperUser,
+	"unwatch":     roleSuperUser,
+}
+
 func (g *GithubNotifier) Register() {
-	zero.OnCommand("github").Handle(func(ctx *zero.Ctx) {
+	engine := newPluginEngine(pluginGithub)
+
+	engine.OnCommand("github").Handle(func(ctx *zero.Ctx) {
 		args := ctx.State["args"].(string)
 		argParts := strings.Fields(args)
 
@@ -108,6 +302,10 @@ func (g *GithubNotifier) Register() {
 			return
 		}
 
+		if !checkRole(ctx, githubCommandRoles[argParts[0]]) {
+			return
+		}
+
 		switch argParts[0] {
 		case "status":
 			g.handleStatusCommand(ctx)
@@ -117,54 +315,85 @@ func (g *GithubNotifier) Register() {
 			g.handleSubscribeCommand(ctx, argParts[1:])
 		case "unsubscribe":
 			g.handleUnsubscribeCommand(ctx, argParts[1:])
+		case "watch":
+			g.handleWatchCommand(ctx, argParts[1:])
+		case "unwatch":
+			g.handleUnwatchCommand(ctx, argParts[1:])
+		case "format":
+			g.handleFormatCommand(ctx, argParts[1:])
+		case "digest":
+			g.handleDigestCommand(ctx, argParts[1:])
+		case "issue":
+			g.handleIssueCommand(ctx, argParts[1:])
+		case "pr":
+			g.handlePullRequestCommand(ctx, argParts[1:])
+		case "release":
+			g.handleReleaseCommand(ctx, argParts[1:])
 		default:
-			ctx.Send("未知操作，支持的操作：status, list, subscribe, unsubscribe")
+			ctx.Send("未知操作，支持的操作：status, list, subscribe, unsubscribe, watch, unwatch, format, digest, issue, pr, release")
 		}
 	})
 
-	if g.notifyConfig.Enabled && len(g.notifyConfig.Repositories) > 0 {
-		go g.startNotifierLoop()
+	if g.config().Unfurl.Enabled {
+		engine.OnRegex(githubURLPattern.String()).SetBlock(false).Handle(g.handleUnfurl)
 	}
+
+	if g.config().Webhook.Enabled {
+		go g.startWebhookServer()
+	}
+
 }
 
 func (g *GithubNotifier) handleStatusCommand(ctx *zero.Ctx) {
-	if !g.notifyConfig.Enabled {
+	if !g.config().Enabled {
 		ctx.Send("GitHub 通知功能未启用")
 		return
 	}
 
-	status := fmt.Sprintf("GitHub 通知功能已启用\n监控仓库数：%d\n通知目标数：%d\n检查间隔：%d分钟",
-		len(g.notifyConfig.Repositories),
-		len(g.notifyConfig.NotifyTargets),
-		g.notifyConfig.Interval)
+	status := fmt.Sprintf("GitHub 通知功能已启用\n监控仓库数：%d\n通知目标数：%d\n检查间隔：%d分钟\n%s",
+		len(g.loadRepositories()),
+		len(g.loadNotifyTargets()),
+		g.config().Interval,
+		g.formatQuota())
 	ctx.Send(status)
 }
 
 func (g *GithubNotifier) handleListCommand(ctx *zero.Ctx) {
-	if !g.notifyConfig.Enabled || len(g.notifyConfig.Repositories) == 0 {
+	repos := g.loadRepositories()
+	if !g.config().Enabled || len(repos) == 0 {
 		ctx.Send("暂无监控的GitHub仓库")
 		return
 	}
 
 	repoList := "监控的GitHub仓库列表：\n"
-	for i, repo := range g.notifyConfig.Repositories {
-		repoList += fmt.Sprintf("%d. %s/%s [%s]\n",
+	for i, repo := range repos {
+		repoList += fmt.Sprintf("%d. %s/%s [%s]",
 			i+1,
 			repo.Owner,
 			repo.Name,
 			strings.Join(repo.MonitorType, ", "))
+		if repo.sourceName() != sourceGithub || repo.BaseURL != "" {
+			repoList += fmt.Sprintf(" (%s %s)", repo.sourceName(), repo.BaseURL)
+		}
+		repoList += "\n"
 	}
 	ctx.Send(repoList)
 }
 
 func (g *GithubNotifier) handleSubscribeCommand(ctx *zero.Ctx, args []string) {
-	if !g.notifyConfig.Enabled {
+	if !g.config().Enabled {
 		ctx.Send("GitHub 通知功能未启用")
 		return
 	}
 
 	if len(args) < 1 {
-		ctx.Send("请指定要订阅的仓库，例如: /github subscribe owner/repo")
+		ctx.Send("请指定要订阅的仓库，例如: /github subscribe owner/repo [--events release,pr] [--branches main] [--labels bug]")
+		return
+	}
+
+	filter, err := parseFilterFlags(args[1:])
+	if err != nil {
+		ctx.Send(err.Error())
 		return
 	}
 
@@ -175,63 +404,67 @@ func (g *GithubNotifier) handleSubscribeCommand(ctx *zero.Ctx, args []string) {
 		return
 	}
 
-	found := false
-	for _, repo := range g.notifyConfig.Repositories {
-		if repo.Owner == parts[0] && repo.Name == parts[1] {
-			found = true
-			break
-		}
-	}
-
+	repo, found := g.findRepository(repoPath)
 	if !found {
 		ctx.Send(fmt.Sprintf("仓库 %s 不在监控列表中", repoPath))
 		return
 	}
+	repoPath = fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
 
-	var targetType string
-	var targetID int64
-
-	if ctx.Event.GroupID != 0 {
-		targetType = "group"
-		targetID = ctx.Event.GroupID
-	} else {
-		targetType = "private"
-		targetID = ctx.Event.UserID
-	}
-
-	var targetIndex = -1
-	for i, target := range g.notifyConfig.NotifyTargets {
-		if target.Type == targetType && target.ID == targetID {
-			targetIndex = i
-			break
-		}
-	}
+	targetType, targetID := getEventTarget(ctx)
 
-	if targetIndex >= 0 {
-		for _, repo := range g.notifyConfig.NotifyTargets[targetIndex].Repos {
-			if repo == repoPath {
+	// a config file subscription can still be narrowed down by a filter stored in the database
+	if filter.IsEmpty() {
+		for _, target := range g.config().NotifyTargets {
+			if target.Type == targetType && target.ID == targetID && target.subscribes(repoPath) {
 				ctx.Send(fmt.Sprintf("已经订阅了仓库 %s", repoPath))
 				return
 			}
 		}
+	}
 
-		g.notifyConfig.NotifyTargets[targetIndex].Repos = append(
-			g.notifyConfig.NotifyTargets[targetIndex].Repos,
-			repoPath,
-		)
-	} else {
-		g.notifyConfig.NotifyTargets = append(g.notifyConfig.NotifyTargets, NotifyTarget{
-			Type:  targetType,
-			ID:    targetID,
-			Repos: []string{repoPath},
-		})
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sub := &repository.GithubSubscription{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Repo:       repoPath,
+		Events:     strings.Join(filter.Events, ","),
+		Branches:   strings.Join(filter.Branches, ","),
+		Labels:     strings.Join(filter.Labels, ","),
 	}
 
-	ctx.Send(fmt.Sprintf("成功订阅仓库 %s", repoPath))
+	err = g.subRepo.Create(reqCtx, sub)
+	if errors.Is(err, repository.ErrSubscriptionExists) {
+		if len(args) == 1 {
+			ctx.Send(fmt.Sprintf("已经订阅了仓库 %s", repoPath))
+			return
+		}
+		if err := g.subRepo.UpdateFilter(reqCtx, sub); err != nil {
+			ctx.Send(fmt.Sprintf("更新订阅时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+			return
+		}
+		ctx.Send(fmt.Sprintf("成功更新仓库 %s 的订阅%s", repoPath, formatFilterSuffix(filter)))
+		return
+	}
+	if err != nil {
+		ctx.Send(fmt.Sprintf("保存订阅时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	ctx.Send(fmt.Sprintf("成功订阅仓库 %s%s", repoPath, formatFilterSuffix(filter)))
+}
+
+func formatFilterSuffix(filter EventFilter) string {
+	if filter.IsEmpty() {
+		return ""
+	}
+	return fmt.Sprintf("（%s）", filter)
 }
 
 func (g *GithubNotifier) handleUnsubscribeCommand(ctx *zero.Ctx, args []string) {
-	if !g.notifyConfig.Enabled {
+	if !g.config().Enabled {
 		ctx.Send("GitHub 通知功能未启用")
 		return
 	}
@@ -241,322 +474,702 @@ func (g *GithubNotifier) handleUnsubscribeCommand(ctx *zero.Ctx, args []string)
 		return
 	}
 
+	// subscriptions are stored under the configured spelling, one for a repository that is no
+	// longer monitored can still be removed by its stored name
 	repoPath := args[0]
+	if repo, found := g.findRepository(repoPath); found {
+		repoPath = fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+	}
+	targetType, targetID := getEventTarget(ctx)
 
-	var targetType string
-	var targetID int64
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	if ctx.Event.GroupID != 0 {
-		targetType = "group"
-		targetID = ctx.Event.GroupID
-	} else {
-		targetType = "private"
-		targetID = ctx.Event.UserID
+	err := g.subRepo.Delete(reqCtx, targetType, targetID, repoPath)
+	if err == nil {
+		ctx.Send(fmt.Sprintf("成功取消订阅仓库 %s", repoPath))
+		return
 	}
 
-	var targetIndex = -1
-	for i, target := range g.notifyConfig.NotifyTargets {
-		if target.Type == targetType && target.ID == targetID {
-			targetIndex = i
-			break
+	if !errors.Is(err, repository.ErrSubscriptionNotFound) {
+		ctx.Send(fmt.Sprintf("取消订阅时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	// subscriptions from the config file can only be changed there
+	for _, target := range g.config().NotifyTargets {
+		if target.Type == targetType && target.ID == targetID && target.subscribes(repoPath) {
+			ctx.Send(fmt.Sprintf("仓库 %s 的订阅来自配置文件，请修改配置文件后重启", repoPath))
+			return
 		}
 	}
 
-	if targetIndex < 0 {
-		ctx.Send("未订阅任何仓库")
-		return
+	ctx.Send(fmt.Sprintf("未订阅仓库 %s", repoPath))
+}
+
+func getEventTarget(ctx *zero.Ctx) (string, int64) {
+	if ctx.Event.GroupID != 0 {
+		return "group", ctx.Event.GroupID
 	}
+	return "private", ctx.Event.UserID
+}
 
-	var repoIndex = -1
-	for i, repo := range g.notifyConfig.NotifyTargets[targetIndex].Repos {
-		if repo == repoPath {
-			repoIndex = i
-			break
+// subscribes reports whether the target receives notifications for repoPath
+func (t NotifyTarget) subscribes(repoPath string) bool {
+	if len(t.Repos) == 0 {
+		return true
+	}
+	for _, repo := range t.Repos {
+		if strings.EqualFold(repo, repoPath) {
+			return true
 		}
 	}
+	return false
+}
 
-	if repoIndex < 0 {
-		ctx.Send(fmt.Sprintf("未订阅仓库 %s", repoPath))
-		return
+// allows reports whether the target should receive the event
+func (t NotifyTarget) allows(event NotifyEvent) bool {
+	if !t.subscribes(event.Repo) || !t.EventFilter.allows(event) {
+		return false
+	}
+	for repo, filter := range t.RepoFilters {
+		if strings.EqualFold(repo, event.Repo) && !filter.allows(event) {
+			return false
+		}
 	}
+	return true
+}
 
-	repos := g.notifyConfig.NotifyTargets[targetIndex].Repos
-	g.notifyConfig.NotifyTargets[targetIndex].Repos = append(
-		repos[:repoIndex],
-		repos[repoIndex+1:]...,
-	)
+// loadNotifyTargets merges targets from the config file with subscriptions stored in the database
+func (g *GithubNotifier) loadNotifyTargets() []NotifyTarget {
+	targets := make([]NotifyTarget, 0, len(g.config().NotifyTargets))
+	for _, target := range g.config().NotifyTargets {
+		target.Repos = append([]string(nil), target.Repos...)
+		targets = append(targets, target)
+	}
 
-	if len(g.notifyConfig.NotifyTargets[targetIndex].Repos) == 0 {
-		g.notifyConfig.NotifyTargets = append(
-			g.notifyConfig.NotifyTargets[:targetIndex],
-			g.notifyConfig.NotifyTargets[targetIndex+1:]...,
-		)
+	if g.subRepo == nil {
+		return targets
 	}
 
-	ctx.Send(fmt.Sprintf("成功取消订阅仓库 %s", repoPath))
-}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-func (g *GithubNotifier) startNotifierLoop() {
-	interval := time.Duration(g.notifyConfig.Interval) * time.Minute
-	if interval < time.Minute {
-		interval = 5 * time.Minute
+	subscriptions, err := g.subRepo.GetAll(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("failed to load github subscriptions")
+		return targets
 	}
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	for _, sub := range subscriptions {
+		index := -1
+		for i, target := range targets {
+			if strings.EqualFold(target.Type, sub.TargetType) && target.ID == sub.TargetID {
+				index = i
+				break
+			}
+		}
 
-	g.checkAllRepositories()
+		if index < 0 {
+			targets = append(targets, NotifyTarget{
+				Type:  sub.TargetType,
+				ID:    sub.TargetID,
+				Repos: []string{sub.Repo},
+			})
+			index = len(targets) - 1
+		} else if !targets[index].subscribes(sub.Repo) {
+			targets[index].Repos = append(targets[index].Repos, sub.Repo)
+		}
 
-	for range ticker.C {
-		g.checkAllRepositories()
+		filter := EventFilter{
+			Events:   splitList(sub.Events),
+			Branches: splitList(sub.Branches),
+			Labels:   splitList(sub.Labels),
+		}
+		if !filter.IsEmpty() {
+			if targets[index].RepoFilters == nil {
+				targets[index].RepoFilters = make(map[string]EventFilter)
+			}
+			targets[index].RepoFilters[sub.Repo] = filter
+		}
 	}
-}
 
-func (g *GithubNotifier) checkAllRepositories() {
-	for _, repo := range g.notifyConfig.Repositories {
-		repoKey := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
-		lastCheckTime, exists := g.lastCheck[repoKey]
-		if !exists {
-			g.lastCheck[repoKey] = time.Now()
+	settings, err := g.subRepo.GetAllTargetSettings(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("failed to load github target settings")
+		return targets
+	}
+
+	for _, setting := range settings {
+		for i := range targets {
+			if strings.EqualFold(targets[i].Type, setting.TargetType) && targets[i].ID == setting.TargetID {
+				targets[i].Format = setting.Format
+			}
+		}
+	}
+
+	if g.digestRepo == nil {
+		return targets
+	}
+
+	digests, err := g.digestRepo.GetAll(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("failed to load github digests")
+		return targets
+	}
+
+	for _, digest := range digests {
+		if digest.Mode == nil {
 			continue
 		}
+		for i := range targets {
+			if strings.EqualFold(targets[i].Type, digest.TargetType) && targets[i].ID == digest.TargetID {
+				targets[i].Digest = *digest.Mode
+			}
+		}
+	}
+
+	return targets
+}
+
+// config returns the current notifier config, it is replaced as a whole on reload
+func (g *GithubNotifier) config() GithubNotifyConfig {
+	g.configMu.RLock()
+	defer g.configMu.RUnlock()
+
+	return g.notifyConfig
+}
+
+// applyConfig swaps in a reloaded config, options that are wired up once at startup keep their old values
+func (g *GithubNotifier) applyConfig(config GithubNotifyConfig) {
+	g.configMu.Lock()
+	old := g.notifyConfig
+	config.Enabled = old.Enabled
+	config.Token = old.Token
+	config.Webhook = old.Webhook
+	config.Unfurl.Enabled = old.Unfurl.Enabled
+	g.notifyConfig = config
+	g.configMu.Unlock()
+
+	// per-repository sources and tokens may have changed
+	g.mu.Lock()
+	g.sources = make(map[string]source.Source)
+	g.defaultBranches = make(map[string]string)
+	g.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"repositories":   len(config.Repositories),
+		"notify_targets": len(config.NotifyTargets),
+		"interval":       config.Interval,
+	}).Info("github notifier config reloaded")
+}
+
+func (g *GithubNotifier) checkAllRepositories() {
+	for _, repo := range g.loadRepositories() {
+		repoPath := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
 
 		for _, monitorType := range repo.MonitorType {
-			switch strings.ToLower(monitorType) {
+			monitorType = strings.ToLower(monitorType)
+
+			// repositories on a paused host are picked up in a later round, cursors keep their place
+			if until, paused := g.transport.rate(repo.apiHost()).pausedUntil(time.Now()); paused {
+				logrus.WithFields(logrus.Fields{
+					"repo":  repoPath,
+					"host":  repo.apiHost(),
+					"until": until.Format("2006-01-02 15:04:05"),
+				}).Warn("api rate limit nearly exhausted, skipping repository this round")
+				break
+			}
+
+			cursor, err := g.loadCursor(repoPath, monitorType)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"repo":         repoPath,
+					"monitor_type": monitorType,
+					"error":        err,
+				}).Error("failed to load github cursor")
+				continue
+			}
+			if cursor == nil {
+				// first time seeing this repository, start from now instead of replaying history
+				continue
+			}
+
+			switch monitorType {
 			case "commit":
-				g.checkCommits(repo.Owner, repo.Name, lastCheckTime)
+				g.checkCommits(repo, cursor)
 			case "release":
-				g.checkReleases(repo.Owner, repo.Name, lastCheckTime)
+				g.checkReleases(repo, cursor)
 			case "issue":
-				g.checkIssues(repo.Owner, repo.Name, lastCheckTime)
+				g.checkIssues(repo, cursor)
 			case "pr":
-				g.checkPullRequests(repo.Owner, repo.Name, lastCheckTime)
+				g.checkPullRequests(repo, cursor)
+			case "workflow":
+				g.checkWorkflowRuns(repo, cursor)
+			default:
+				continue
 			}
+
+			g.saveCursor(cursor)
 		}
+	}
+}
+
+// loadCursor returns the stored cursor, or nil after initializing a new one at the current time
+func (g *GithubNotifier) loadCursor(repoPath, monitorType string) (*repository.GithubCursor, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := g.cursorRepo.Get(ctx, repoPath, monitorType)
+	if err == nil {
+		return cursor, nil
+	}
+	if !errors.Is(err, repository.ErrCursorNotFound) {
+		return nil, err
+	}
 
-		g.lastCheck[repoKey] = time.Now()
+	cursor = &repository.GithubCursor{
+		Repo:        repoPath,
+		MonitorType: monitorType,
+		LastTime:    time.Now(),
 	}
+	if err := g.cursorRepo.Save(ctx, cursor); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
 }
 
-func (g *GithubNotifier) checkCommits(owner, repo string, since time.Time) {
-	ctx := context.Background()
-	opts := &github.CommitsListOptions{
-		Since: since,
-		ListOptions: github.ListOptions{
-			PerPage: 10,
-		},
+func (g *GithubNotifier) saveCursor(cursor *repository.GithubCursor) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := g.cursorRepo.Save(ctx, cursor); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"repo":         cursor.Repo,
+			"monitor_type": cursor.MonitorType,
+			"error":        err,
+		}).Error("failed to save github cursor")
 	}
+}
+
+// markDelivered records the event and reports whether it has not been delivered before
+func (g *GithubNotifier) markDelivered(event NotifyEvent, eventKey string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	commits, _, err := g.client.Repositories.ListCommits(ctx, owner, repo, opts)
+	fresh, err := g.cursorRepo.MarkDelivered(ctx, event.Repo, event.Type, eventKey)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
-			"owner": owner,
-			"repo":  repo,
-			"error": err,
-		}).Error("failed to check commits")
+			"repo":      event.Repo,
+			"event_key": eventKey,
+			"error":     err,
+		}).Error("failed to mark github event delivered")
+		return false
+	}
+	if fresh {
+		g.recordEvent(event)
+	}
+
+	return fresh
+}
+
+// deliver sends the message unless the event has already been delivered before
+func (g *GithubNotifier) deliver(event NotifyEvent, eventKey, messageContent string, card *utils.GithubCard) {
+	if g.markDelivered(event, eventKey) {
+		g.sendToTargets(messageContent, event, card)
+	}
+}
+
+type commitSummary struct {
+	SHA     string
+	Message string
+	Author  string
+	Time    time.Time
+	URL     string
+}
+
+// deliverCommits sends the undelivered commits of one push or polling round as a single message
+func (g *GithubNotifier) deliverCommits(repoPath, branch string, commits []commitSummary, compareURL string) {
+	fresh := make([]commitSummary, 0, len(commits))
+	for _, commit := range commits {
+		event := NotifyEvent{
+			Repo:   repoPath,
+			Type:   "commit",
+			Branch: branch,
+			Action: "pushed",
+			Title:  commitSubject(commit.Message),
+			Author: commit.Author,
+			URL:    commit.URL,
+			Time:   commit.Time,
+		}
+		if g.markDelivered(event, commit.SHA) {
+			fresh = append(fresh, commit)
+		}
+	}
+
+	var msg string
+	switch len(fresh) {
+	case 0:
 		return
+	case 1:
+		msg = formatCommitMessage(repoPath, fresh[0].Author, fresh[0].Time, fresh[0].Message, fresh[0].URL)
+	default:
+		msg = formatCommitBatchMessage(repoPath, branch, fresh, compareURL)
 	}
 
-	if len(commits) == 0 {
+	g.sendToTargets(msg, NotifyEvent{Repo: repoPath, Type: "commit", Branch: branch}, nil)
+}
+
+func commitSubject(commitMessage string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(commitMessage), "\n")
+	return strings.TrimSpace(subject)
+}
+
+func (g *GithubNotifier) checkCommits(repo RepoConfig, cursor *repository.GithubCursor) {
+	ctx := context.Background()
+	src := g.sourceFor(repo)
+
+	commits, err := src.Commits(ctx, repo.Owner, repo.Name, cursor.LastTime)
+	limit, err := splitPageLimit(err)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"owner": repo.Owner,
+			"repo":  repo.Name,
+			"error": err,
+		}).Error("failed to check commits")
 		return
 	}
 
-	for i := len(commits) - 1; i >= 0; i-- {
-		commit := commits[i]
-		if commit.Commit == nil || commit.Commit.Message == nil {
-			continue
-		}
+	repoPath := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+	branch := g.defaultBranch(repo)
+	previousSHA := cursor.LastID
 
-		commitTime := commit.Commit.Author.GetDate()
-		if commitTime.Before(since) || commitTime.Equal(since) {
+	summaries := make([]commitSummary, 0, len(commits))
+	for _, commit := range commits {
+		if commit.SHA == cursor.LastID {
 			continue
 		}
 
-		msg := fmt.Sprintf("🔄 GitHub 提交更新\n仓库：%s/%s\n作者：%s\n提交时间：%s\n\n%s\n\n详情：%s",
-			owner,
-			repo,
-			commit.Commit.Author.GetName(),
-			commitTime.Format("2006-01-02 15:04:05"),
-			*commit.Commit.Message,
-			commit.GetHTMLURL())
+		summaries = append(summaries, commitSummary{
+			SHA:     commit.SHA,
+			Message: commit.Message,
+			Author:  commit.Author,
+			Time:    commit.AuthoredAt,
+			URL:     commit.URL,
+		})
 
-		repoPath := fmt.Sprintf("%s/%s", owner, repo)
-		g.sendToTargets(msg, repoPath)
+		cursor.LastID = commit.SHA
+		if commit.CommittedAt.After(cursor.LastTime) {
+			cursor.LastTime = commit.CommittedAt
+		}
+	}
+	if len(summaries) > 0 {
+		compareURL := src.CommitsURL(repo.Owner, repo.Name, branch)
+		if previousSHA != "" {
+			compareURL = src.CompareURL(repo.Owner, repo.Name, previousSHA, cursor.LastID)
+		}
+		g.deliverCommits(repoPath, branch, summaries, compareURL)
 	}
+
+	g.reportPageLimit(repoPath, cursor.MonitorType, limit)
 }
 
-func (g *GithubNotifier) checkReleases(owner, repo string, since time.Time) {
+func (g *GithubNotifier) checkReleases(repo RepoConfig, cursor *repository.GithubCursor) {
 	ctx := context.Background()
-	opts := &github.ListOptions{
-		PerPage: 5,
+
+	lastID, _ := strconv.ParseInt(cursor.LastID, 10, 64)
+	known := func(release source.Release) bool {
+		if lastID != 0 && release.ID != 0 {
+			return release.ID <= lastID
+		}
+		return !release.CreatedAt.After(cursor.LastTime)
 	}
 
-	releases, _, err := g.client.Repositories.ListReleases(ctx, owner, repo, opts)
+	releases, err := g.sourceFor(repo).Releases(ctx, repo.Owner, repo.Name, known)
+	limit, err := splitPageLimit(err)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
-			"owner": owner,
-			"repo":  repo,
+			"owner": repo.Owner,
+			"repo":  repo.Name,
 			"error": err,
 		}).Error("failed to check releases")
 		return
 	}
 
+	repoPath := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
 	for _, release := range releases {
-		releaseTime := release.GetCreatedAt().Time
-		if releaseTime.Before(since) || releaseTime.Equal(since) {
-			continue
+		msg := formatReleaseMessage(repoPath,
+			release.Name,
+			release.CreatedAt,
+			release.Body,
+			release.URL)
+
+		event := NotifyEvent{
+			Repo:   repoPath,
+			Type:   cursor.MonitorType,
+			Action: "published",
+			Title:  release.Title(),
+			Author: release.Author,
+			URL:    release.URL,
+			Time:   release.CreatedAt,
 		}
+		g.deliver(event, release.Key, msg, releaseCard(repoPath, release))
 
-		msg := fmt.Sprintf("🚀 GitHub 新版本发布\n仓库：%s/%s\n版本：%s\n发布时间：%s\n\n%s\n\n详情：%s",
-			owner,
-			repo,
-			release.GetName(),
-			releaseTime.Format("2006-01-02 15:04:05"),
-			release.GetBody(),
-			release.GetHTMLURL())
-
-		repoPath := fmt.Sprintf("%s/%s", owner, repo)
-		g.sendToTargets(msg, repoPath)
+		if release.ID > lastID {
+			lastID = release.ID
+			cursor.LastID = strconv.FormatInt(lastID, 10)
+		}
+		if release.CreatedAt.After(cursor.LastTime) {
+			cursor.LastTime = release.CreatedAt
+		}
 	}
+
+	g.reportPageLimit(repoPath, cursor.MonitorType, limit)
 }
 
-func (g *GithubNotifier) checkIssues(owner, repo string, since time.Time) {
+func (g *GithubNotifier) checkIssues(repo RepoConfig, cursor *repository.GithubCursor) {
 	ctx := context.Background()
-	opts := &github.IssueListByRepoOptions{
-		Since: since,
-		State: "all",
-		ListOptions: github.ListOptions{
-			PerPage: 5,
-		},
-	}
+	since := cursor.LastTime
 
-	issues, _, err := g.client.Issues.ListByRepo(ctx, owner, repo, opts)
+	issues, err := g.sourceFor(repo).Issues(ctx, repo.Owner, repo.Name, since)
+	limit, err := splitPageLimit(err)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
-			"owner": owner,
-			"repo":  repo,
+			"owner": repo.Owner,
+			"repo":  repo.Name,
 			"error": err,
 		}).Error("failed to check issues")
 		return
 	}
 
+	repoPath := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
 	for _, issue := range issues {
-		if issue.IsPullRequest() {
-			continue
+		action := "updated"
+		if !issue.CreatedAt.Before(since) {
+			action = "opened"
+		} else if issue.State == "closed" && !issue.ClosedAt.Before(since) {
+			action = "closed"
 		}
 
-		issueTime := issue.GetCreatedAt()
-		updateTime := issue.GetUpdatedAt()
-
-		if (issueTime.Before(since) || issueTime.Equal(since)) &&
-			(updateTime.Before(since) || updateTime.Equal(since)) {
-			continue
+		msg := formatIssueMessage(pollActionNames[action],
+			repoPath,
+			issue.Title,
+			issue.State,
+			issue.Author,
+			issue.CreatedAt,
+			issue.URL)
+
+		event := NotifyEvent{
+			Repo:   repoPath,
+			Type:   cursor.MonitorType,
+			Labels: labelNames(issue.Labels),
+			Action: action,
+			Title:  fmt.Sprintf("#%d %s", issue.Number, issue.Title),
+			Author: issue.Author,
+			URL:    issue.URL,
+			Time:   issue.UpdatedAt,
 		}
+		eventKey := fmt.Sprintf("%d@%d", issue.Number, issue.UpdatedAt.Unix())
+		g.deliver(event, eventKey, msg, issueCard(repoPath, issue))
 
-		action := "更新"
-		if issueTime.After(since) {
-			action = "创建"
+		if issue.UpdatedAt.After(cursor.LastTime) {
+			cursor.LastTime = issue.UpdatedAt
 		}
-
-		msg := fmt.Sprintf("📝 GitHub Issue %s\n仓库：%s/%s\n标题：%s\n状态：%s\n创建者：%s\n%s时间：%s\n\n详情：%s",
-			action,
-			owner,
-			repo,
-			issue.GetTitle(),
-			issue.GetState(),
-			issue.GetUser().GetLogin(),
-			action,
-			issueTime.Format("2006-01-02 15:04:05"),
-			issue.GetHTMLURL())
-
-		repoPath := fmt.Sprintf("%s/%s", owner, repo)
-		g.sendToTargets(msg, repoPath)
 	}
+
+	g.reportPageLimit(repoPath, cursor.MonitorType, limit)
 }
 
-func (g *GithubNotifier) checkPullRequests(owner, repo string, since time.Time) {
+func (g *GithubNotifier) checkPullRequests(repo RepoConfig, cursor *repository.GithubCursor) {
 	ctx := context.Background()
-	opts := &github.PullRequestListOptions{
-		State: "all",
-		ListOptions: github.ListOptions{
-			PerPage: 5,
-		},
-	}
+	since := cursor.LastTime
+	src := g.sourceFor(repo)
 
-	prs, _, err := g.client.PullRequests.List(ctx, owner, repo, opts)
+	prs, err := src.PullRequests(ctx, repo.Owner, repo.Name, since)
+	limit, err := splitPageLimit(err)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
-			"owner": owner,
-			"repo":  repo,
+			"owner": repo.Owner,
+			"repo":  repo.Name,
 			"error": err,
 		}).Error("failed to check pull requests")
 		return
 	}
 
+	repoPath := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
 	for _, pr := range prs {
-		prTime := pr.GetCreatedAt()
-		updateTime := pr.GetUpdatedAt()
+		action := "updated"
+		if !pr.CreatedAt.Before(since) {
+			action = "opened"
+		} else if pr.Merged() && !pr.MergedAt.Before(since) {
+			action = "merged"
+		} else if pr.State == "closed" && !pr.ClosedAt.Before(since) {
+			action = "closed"
+		}
 
-		if (prTime.Before(since) || prTime.Equal(since)) &&
-			(updateTime.Before(since) || updateTime.Equal(since)) {
-			continue
+		msg := formatPullRequestMessage(pollActionNames[action],
+			repoPath,
+			pr.Title,
+			pr.State,
+			pr.Author,
+			pr.CreatedAt,
+			pr.URL)
+
+		// list results carry no diffstat, fetch the full pull request when a card will be rendered
+		if !pr.HasDiffStat && g.hasImageTargets() {
+			if full, err := src.PullRequest(ctx, repo.Owner, repo.Name, pr.Number); err == nil {
+				pr = *full
+			}
+		}
+
+		event := NotifyEvent{
+			Repo:   repoPath,
+			Type:   cursor.MonitorType,
+			Labels: labelNames(pr.Labels),
+			Action: action,
+			Title:  fmt.Sprintf("#%d %s", pr.Number, pr.Title),
+			Author: pr.Author,
+			URL:    pr.URL,
+			Time:   pr.UpdatedAt,
 		}
+		eventKey := fmt.Sprintf("%d@%d", pr.Number, pr.UpdatedAt.Unix())
+		g.deliver(event, eventKey, msg, pullRequestCard(repoPath, pr))
 
-		action := "更新"
-		if prTime.After(since) {
-			action = "创建"
+		if pr.UpdatedAt.After(cursor.LastTime) {
+			cursor.LastTime = pr.UpdatedAt
 		}
+	}
 
-		msg := fmt.Sprintf("🔀 GitHub Pull Request %s\n仓库：%s/%s\n标题：%s\n状态：%s\n创建者：%s\n%s时间：%s\n\n详情：%s",
-			action,
-			owner,
-			repo,
-			pr.GetTitle(),
-			pr.GetState(),
-			pr.GetUser().GetLogin(),
-			action,
-			prTime.Format("2006-01-02 15:04:05"),
-			pr.GetHTMLURL())
+	g.reportPageLimit(repoPath, cursor.MonitorType, limit)
+}
 
-		repoPath := fmt.Sprintf("%s/%s", owner, repo)
-		g.sendToTargets(msg, repoPath)
+// splitPageLimit separates a page limit from real errors, the items read before it are still delivered
+func splitPageLimit(err error) (*source.PageLimitError, error) {
+	var limit *source.PageLimitError
+	if errors.As(err, &limit) {
+		return limit, nil
 	}
+	return nil, err
 }
 
-func (g *GithubNotifier) sendToTargets(messageContent string, repoPath string) {
-	for _, target := range g.notifyConfig.NotifyTargets {
-		shouldSend := len(target.Repos) == 0
+// reportPageLimit tells subscribers when a poll had to skip items because of the page limit.
+// Lists read oldest first lose nothing, the cursor stops at the last delivered item.
+func (g *GithubNotifier) reportPageLimit(repoPath, monitorType string, limit *source.PageLimitError) {
+	if limit == nil {
+		return
+	}
 
-		if !shouldSend {
-			for _, subscribedRepo := range target.Repos {
-				if subscribedRepo == repoPath {
-					shouldSend = true
-					break
-				}
-			}
-		}
+	fields := logrus.Fields{
+		"repo":         repoPath,
+		"monitor_type": monitorType,
+		"pages":        source.MaxPages,
+		"remaining":    limit.Remaining,
+	}
+	if limit.Oldest {
+		logrus.WithFields(fields).Info("page limit reached, continuing from the last delivered item on the next poll")
+		return
+	}
+	logrus.WithFields(fields).Warn("page limit reached, older items are skipped")
 
-		if !shouldSend {
-			continue
-		}
+	skipped := "更早的"
+	if limit.Remaining > 0 {
+		skipped = fmt.Sprintf("约 %d 条更早的", limit.Remaining)
+	}
+	msg := fmt.Sprintf("⚠️ [%s] 一次轮询最多读取 %d 页，%s%s未推送", repoPath, source.MaxPages, skipped, monitorTypeNames[monitorType])
 
-		switch strings.ToLower(target.Type) {
-		case "group":
-			g.bot.SendGroupMessage(target.ID, message.Text(messageContent))
-		case "private":
-			g.bot.SendPrivateMessage(target.ID, message.Text(messageContent))
-		default:
-			logrus.WithFields(logrus.Fields{
-				"target_type": target.Type,
-				"target_id":   target.ID,
-			}).Warn("unknown notify target type")
+	g.sendToTargets(msg, NotifyEvent{Repo: repoPath, Type: monitorType}, nil)
+}
+
+var monitorTypeNames = map[string]string{
+	"commit":   "提交",
+	"release":  "发布",
+	"issue":    "Issue",
+	"pr":       "Pull Request",
+	"workflow": "工作流运行",
+}
+
+var pollActionNames = map[string]string{
+	"opened":  "创建",
+	"closed":  "关闭",
+	"merged":  "合并",
+	"updated": "更新",
+}
+
+func labelNames(labels []source.Label) []string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+	return names
+}
+
+func formatCommitMessage(repoPath, author string, commitTime time.Time, commitMessage, url string) string {
+	return fmt.Sprintf("🔄 GitHub 提交更新\n仓库：%s\n作者：%s\n提交时间：%s\n\n%s\n\n详情：%s",
+		repoPath,
+		author,
+		commitTime.Format("2006-01-02 15:04:05"),
+		commitMessage,
+		url)
+}
+
+func formatCommitBatchMessage(repoPath, branch string, commits []commitSummary, url string) string {
+	var shortlog strings.Builder
+	for i, commit := range commits {
+		if i == maxShortlogLines {
+			shortlog.WriteString(fmt.Sprintf("\n…… 以及另外 %d 个提交", len(commits)-maxShortlogLines))
+			break
 		}
+		if i > 0 {
+			shortlog.WriteString("\n")
+		}
+		shortlog.WriteString(fmt.Sprintf("%.7s %s (%s)", commit.SHA, commitSubject(commit.Message), commit.Author))
 	}
+
+	return fmt.Sprintf("🔄 GitHub 提交更新\n仓库：%s\n分支：%s\n共 %d 个新提交\n\n%s\n\n详情：%s",
+		repoPath,
+		branch,
+		len(commits),
+		shortlog.String(),
+		url)
+}
+
+func formatReleaseMessage(repoPath, name string, releaseTime time.Time, body, url string) string {
+	return fmt.Sprintf("🚀 GitHub 新版本发布\n仓库：%s\n版本：%s\n发布时间：%s\n\n%s\n\n详情：%s",
+		repoPath,
+		name,
+		releaseTime.Format("2006-01-02 15:04:05"),
+		body,
+		url)
+}
+
+func formatIssueMessage(action, repoPath, title, state, creator string, actionTime time.Time, url string) string {
+	return fmt.Sprintf("📝 GitHub Issue %s\n仓库：%s\n标题：%s\n状态：%s\n创建者：%s\n%s时间：%s\n\n详情：%s",
+		action,
+		repoPath,
+		title,
+		state,
+		creator,
+		action,
+		actionTime.Format("2006-01-02 15:04:05"),
+		url)
+}
+
+func formatPullRequestMessage(action, repoPath, title, state, creator string, actionTime time.Time, url string) string {
+	return fmt.Sprintf("🔀 GitHub Pull Request %s\n仓库：%s\n标题：%s\n状态：%s\n创建者：%s\n%s时间：%s\n\n详情：%s",
+		action,
+		repoPath,
+		title,
+		state,
+		creator,
+		action,
+		actionTime.Format("2006-01-02 15:04:05"),
+		url)
 }
 
 // Reserved methods for backward compatibility
 func (g *GithubNotifier) sendToAllTargets(messageContent string) {
-	for _, target := range g.notifyConfig.NotifyTargets {
+	for _, target := range g.loadNotifyTargets() {
 		switch strings.ToLower(target.Type) {
 		case "group":
 			g.bot.SendGroupMessage(target.ID, message.Text(messageContent))
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// requests kept aside for chat commands once polling backs off
+	rateLimitReserve = 5
+
+	// conditional request cache entries kept per host before evicting old ones
+	maxETagCacheEntries = 512
+
+	// host of the github.com API, quotas of other servers are tracked under their own host
+	githubAPIHost = "api.github.com"
+)
+
+// rateTracker follows the core quota reported in the X-RateLimit-* response headers
+type rateTracker struct {
+	mu         sync.Mutex
+	known      bool
+	limit      int
+	remaining  int
+	reset      time.Time
+	retryAfter time.Time // secondary rate limit backoff
+}
+
+func (r *rateTracker) update(resp *http.Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			r.retryAfter = time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+	}
+
+	// search and graphql have quotas of their own
+	if resource := resp.Header.Get("X-RateLimit-Resource"); resource != "" && resource != "core" {
+		return
+	}
+
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	r.known = true
+	r.limit = limit
+	r.remaining = remaining
+	r.reset = time.Unix(reset, 0)
+}
+
+// pausedUntil reports whether polling should back off and until when
+func (r *rateTracker) pausedUntil(now time.Time) (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.retryAfter.After(now) {
+		return r.retryAfter, true
+	}
+	if r.known && r.remaining <= rateLimitReserve && r.reset.After(now) {
+		return r.reset, true
+	}
+	return time.Time{}, false
+}
+
+func (r *rateTracker) snapshot() (limit, remaining int, reset time.Time, known bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.limit, r.remaining, r.reset, r.known
+}
+
+type cachedResponse struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// etagTransport sends conditional GET requests and replays the cached body on 304,
+// which GitHub does not count against the rate limit. Quotas and cached responses are kept
+// per host, so one server running low does not hold back the others.
+type etagTransport struct {
+	base http.RoundTripper
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState is the quota and the conditional request cache of one API host
+type hostState struct {
+	rate rateTracker
+
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+	order   []string
+}
+
+func newETagTransport(base http.RoundTripper) *etagTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &etagTransport{
+		base:  base,
+		hosts: make(map[string]*hostState),
+	}
+}
+
+func (t *etagTransport) host(name string) *hostState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.hosts[name]
+	if !ok {
+		state = &hostState{entries: make(map[string]*cachedResponse)}
+		t.hosts[name] = state
+	}
+	return state
+}
+
+// rate returns the quota tracker of an API host such as api.github.com
+func (t *etagTransport) rate(host string) *rateTracker {
+	return &t.host(host).rate
+}
+
+type pausedHost struct {
+	name  string
+	until time.Time
+}
+
+// pausedHosts lists the hosts polling currently backs off from, sorted by name
+func (t *etagTransport) pausedHosts(now time.Time) []pausedHost {
+	t.mu.Lock()
+	names := make([]string, 0, len(t.hosts))
+	for name := range t.hosts {
+		names = append(names, name)
+	}
+	t.mu.Unlock()
+	sort.Strings(names)
+
+	var paused []pausedHost
+	for _, name := range names {
+		if until, ok := t.rate(name).pausedUntil(now); ok {
+			paused = append(paused, pausedHost{name: name, until: until})
+		}
+	}
+	return paused
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	state := t.host(req.URL.Host)
+
+	if req.Method != http.MethodGet {
+		resp, err := t.base.RoundTrip(req)
+		if err == nil {
+			state.rate.update(resp)
+		}
+		return resp, err
+	}
+
+	key := req.URL.String()
+
+	state.mu.Lock()
+	cached := state.entries[key]
+	state.mu.Unlock()
+
+	if cached != nil {
+		// RoundTrip must not modify the caller's request
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	state.rate.update(resp)
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+
+		header := cached.header.Clone()
+		for _, name := range []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-RateLimit-Used", "X-RateLimit-Resource"} {
+			if value := resp.Header.Get(name); value != "" {
+				header.Set(name, value)
+			}
+		}
+
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(cached.body)),
+			ContentLength: int64(len(cached.body)),
+			Request:       req,
+		}, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	state.store(key, &cachedResponse{
+		etag:   etag,
+		header: resp.Header.Clone(),
+		body:   body,
+	})
+
+	return resp, nil
+}
+
+func (h *hostState) store(key string, entry *cachedResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.entries[key]; !ok {
+		h.order = append(h.order, key)
+	}
+	h.entries[key] = entry
+
+	for len(h.order) > maxETagCacheEntries {
+		delete(h.entries, h.order[0])
+		h.order = h.order[1:]
+	}
+}
+
+// formatQuota describes the remaining core API quota for /github status
+func (g *GithubNotifier) formatQuota() string {
+	rate := g.transport.rate(githubAPIHost)
+	limit, remaining, reset, known := rate.snapshot()
+	if !known {
+		// querying the rate limit endpoint does not count against the quota
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		limits, _, err := g.client.RateLimits(ctx)
+		if err != nil || limits.GetCore() == nil {
+			return "API 配额：暂无数据"
+		}
+		core := limits.GetCore()
+		limit, remaining, reset = core.Limit, core.Remaining, core.Reset.Time
+	}
+
+	quota := fmt.Sprintf("API 配额：剩余 %d/%d，%s 重置", remaining, limit, reset.Format("15:04:05"))
+	if until, paused := rate.pausedUntil(time.Now()); paused {
+		quota += fmt.Sprintf("\n轮询已暂停至 %s", until.Format("15:04:05"))
+	}
+	for _, host := range g.transport.pausedHosts(time.Now()) {
+		if host.name == githubAPIHost {
+			continue
+		}
+		quota += fmt.Sprintf("\n%s 轮询已暂停至 %s", host.name, host.until.Format("15:04:05"))
+	}
+	return quota
+}
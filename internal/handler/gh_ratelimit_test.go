@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestETagTransportTracksHostsSeparately(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	exhausted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", reset)
+		w.Write([]byte(`[]`))
+	}))
+	defer exhausted.Close()
+
+	requests := 0
+	cached := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`["cached"]`))
+	}))
+	defer cached.Close()
+
+	transport := newETagTransport(nil)
+	client := &http.Client{Transport: transport}
+
+	get := func(rawURL string) string {
+		t.Helper()
+		resp, err := client.Get(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	get(exhausted.URL + "/repos")
+	get(cached.URL + "/repos")
+	if body := get(cached.URL + "/repos"); body != `["cached"]` {
+		t.Errorf("replayed body = %q", body)
+	}
+	if requests != 2 {
+		t.Errorf("cached server saw %d requests, want 2", requests)
+	}
+
+	now := time.Now()
+	if _, paused := transport.rate(host(t, exhausted.URL)).pausedUntil(now); !paused {
+		t.Error("exhausted host is not paused")
+	}
+	if _, paused := transport.rate(host(t, cached.URL)).pausedUntil(now); paused {
+		t.Error("host with quota left is paused by another host")
+	}
+	if _, paused := transport.rate(githubAPIHost).pausedUntil(now); paused {
+		t.Error("github.com is paused by another host")
+	}
+
+	// a path served by both hosts must not share a cache entry
+	if body := get(exhausted.URL + "/repos"); body != `[]` {
+		t.Errorf("body = %q, want the response of its own host", body)
+	}
+}
+
+func host(t *testing.T, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"PakuchiBot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+	zero "github.com/wdvxdr1123/ZeroBot"
+)
+
+var supportedMonitorTypes = []string{"commit", "release", "issue", "pr", "workflow"}
+
+func isSupportedMonitorType(monitorType string) bool {
+	for _, t := range supportedMonitorTypes {
+		if t == monitorType {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RepoConfig) monitors(monitorType string) bool {
+	for _, t := range r.MonitorType {
+		if strings.EqualFold(t, monitorType) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadRepositories merges repositories from the config file with those added via /github watch
+func (g *GithubNotifier) loadRepositories() []RepoConfig {
+	repos := make([]RepoConfig, 0, len(g.config().Repositories))
+	repos = append(repos, g.config().Repositories...)
+
+	if g.watchRepo == nil {
+		return repos
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	watched, err := g.watchRepo.GetAll(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("failed to load watched github repositories")
+		return repos
+	}
+
+	for _, w := range watched {
+		if g.isConfiguredRepository(w.Owner, w.Name) {
+			continue
+		}
+		repos = append(repos, RepoConfig{
+			Owner:       w.Owner,
+			Name:        w.Name,
+			MonitorType: w.MonitorTypes(),
+		})
+	}
+
+	return repos
+}
+
+func (g *GithubNotifier) isConfiguredRepository(owner, name string) bool {
+	for _, repo := range g.config().Repositories {
+		if strings.EqualFold(repo.Owner, owner) && strings.EqualFold(repo.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GithubNotifier) findRepository(fullName string) (RepoConfig, bool) {
+	for _, repo := range g.loadRepositories() {
+		if strings.EqualFold(fmt.Sprintf("%s/%s", repo.Owner, repo.Name), fullName) {
+			return repo, true
+		}
+	}
+	return RepoConfig{}, false
+}
+
+func (g *GithubNotifier) handleWatchCommand(ctx *zero.Ctx, args []string) {
+	if !g.config().Enabled {
+		ctx.Send("GitHub 通知功能未启用")
+		return
+	}
+
+	if len(args) < 2 {
+		ctx.Send(fmt.Sprintf("请指定仓库和监控类型，例如: /github watch owner/repo commit,release,pr\n支持的类型：%s",
+			strings.Join(supportedMonitorTypes, ", ")))
+		return
+	}
+
+	parts := strings.Split(args[0], "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		ctx.Send("仓库格式错误，应为 owner/repo")
+		return
+	}
+
+	monitorTypes := make([]string, 0)
+	for _, t := range strings.Split(strings.ToLower(args[1]), ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !isSupportedMonitorType(t) {
+			ctx.Send(fmt.Sprintf("不支持的监控类型 %s，支持的类型：%s", t, strings.Join(supportedMonitorTypes, ", ")))
+			return
+		}
+		monitorTypes = append(monitorTypes, t)
+	}
+	if len(monitorTypes) == 0 {
+		ctx.Send("请至少指定一种监控类型")
+		return
+	}
+
+	if g.isConfiguredRepository(parts[0], parts[1]) {
+		ctx.Send(fmt.Sprintf("仓库 %s 已在配置文件的监控列表中", args[0]))
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	repo, resp, err := g.client.Repositories.Get(reqCtx, parts[0], parts[1])
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			ctx.Send(fmt.Sprintf("仓库 %s 不存在或无权访问", args[0]))
+			return
+		}
+		ctx.Send(fmt.Sprintf("校验仓库时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+	if err := g.watchRepo.Upsert(reqCtx, owner, name, monitorTypes, ctx.Event.UserID); err != nil {
+		ctx.Send(fmt.Sprintf("保存监控仓库时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	ctx.Send(fmt.Sprintf("成功添加监控仓库 %s/%s [%s]", owner, name, strings.Join(monitorTypes, ", ")))
+}
+
+func (g *GithubNotifier) handleUnwatchCommand(ctx *zero.Ctx, args []string) {
+	if !g.config().Enabled {
+		ctx.Send("GitHub 通知功能未启用")
+		return
+	}
+
+	if len(args) < 1 {
+		ctx.Send("请指定要移除的仓库，例如: /github unwatch owner/repo")
+		return
+	}
+
+	parts := strings.Split(args[0], "/")
+	if len(parts) != 2 {
+		ctx.Send("仓库格式错误，应为 owner/repo")
+		return
+	}
+
+	if g.isConfiguredRepository(parts[0], parts[1]) {
+		ctx.Send(fmt.Sprintf("仓库 %s 来自配置文件，请修改配置文件后重启", args[0]))
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := g.watchRepo.Delete(reqCtx, parts[0], parts[1]); err != nil {
+		if errors.Is(err, repository.ErrWatchedRepoNotFound) {
+			ctx.Send(fmt.Sprintf("仓库 %s 不在监控列表中", args[0]))
+			return
+		}
+		ctx.Send(fmt.Sprintf("移除监控仓库时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	ctx.Send(fmt.Sprintf("成功移除监控仓库 %s", args[0]))
+}
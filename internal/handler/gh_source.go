@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"PakuchiBot/internal/source"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	sourceGithub = "github"
+	sourceGitea  = "gitea"
+	sourceGitlab = "gitlab"
+)
+
+func (r RepoConfig) sourceName() string {
+	if r.Source == "" {
+		return sourceGithub
+	}
+	return strings.ToLower(r.Source)
+}
+
+// isPublicGithub reports whether the repository is served by the shared github.com client
+func (r RepoConfig) isPublicGithub() bool {
+	return r.sourceName() == sourceGithub && r.BaseURL == "" && r.Token == ""
+}
+
+// apiHost is the host the repository's API requests go to, quotas are tracked per host
+func (r RepoConfig) apiHost() string {
+	if r.BaseURL != "" {
+		if u, err := url.Parse(r.BaseURL); err == nil && u.Host != "" {
+			return u.Host
+		}
+	}
+	if r.sourceName() == sourceGitlab {
+		return "gitlab.com"
+	}
+	return githubAPIHost
+}
+
+// sourceFor returns the source of a repository, clients are shared between repositories on the same server
+func (g *GithubNotifier) sourceFor(repo RepoConfig) source.Source {
+	token := repo.Token
+	if token == "" && repo.sourceName() == sourceGithub {
+		token = g.config().Token
+	}
+	key := fmt.Sprintf("%s|%s|%s", repo.sourceName(), repo.BaseURL, token)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if src, ok := g.sources[key]; ok {
+		return src
+	}
+
+	var src source.Source
+	httpClient := &http.Client{Transport: g.transport}
+
+	switch repo.sourceName() {
+	case sourceGitea:
+		src = source.NewGitea(repo.BaseURL, token, httpClient)
+	case sourceGitlab:
+		src = source.NewGitlab(repo.BaseURL, token, httpClient)
+	default:
+		if repo.isPublicGithub() {
+			src = source.NewGithub(g.client, "")
+			break
+		}
+		if token != "" {
+			ts := github.BasicAuthTransport{
+				Username:  token,
+				Password:  "x-oauth-basic",
+				Transport: g.transport,
+			}
+			httpClient = ts.Client()
+		}
+		client := github.NewClient(httpClient)
+		if repo.BaseURL != "" {
+			enterprise, err := github.NewEnterpriseClient(repo.BaseURL, repo.BaseURL, httpClient)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"base_url": repo.BaseURL,
+					"error":    err,
+				}).Error("invalid github enterprise base url, falling back to github.com")
+			} else {
+				client = enterprise
+			}
+		}
+		src = source.NewGithub(client, repo.BaseURL)
+	}
+
+	g.sources[key] = src
+	return src
+}
+
+// defaultBranch returns the branch that polled commits are listed from
+func (g *GithubNotifier) defaultBranch(repo RepoConfig) string {
+	repoPath := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+
+	g.mu.Lock()
+	branch, ok := g.defaultBranches[repoPath]
+	g.mu.Unlock()
+	if ok {
+		return branch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	branch, err := g.sourceFor(repo).DefaultBranch(ctx, repo.Owner, repo.Name)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"owner": repo.Owner,
+			"repo":  repo.Name,
+			"error": err,
+		}).Warn("failed to get default branch")
+		return ""
+	}
+
+	g.mu.Lock()
+	g.defaultBranches[repoPath] = branch
+	g.mu.Unlock()
+
+	return branch
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"PakuchiBot/internal/source"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultWebhookListen = ":8080"
+	defaultWebhookPath   = "/github/webhook"
+
+	// GitHub caps webhook payloads at 25 MB
+	maxWebhookPayloadSize = 25 << 20
+)
+
+func (g *GithubNotifier) startWebhookServer() {
+	listen := g.config().Webhook.Listen
+	if listen == "" {
+		listen = defaultWebhookListen
+	}
+
+	path := g.config().Webhook.Path
+	if path == "" {
+		path = defaultWebhookPath
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, g)
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Printf("GitHub webhook server listening on %s%s", listen, path)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logrus.WithError(err).Error("github webhook server stopped")
+	}
+}
+
+// ServeHTTP receives GitHub webhook deliveries and routes them to the notify targets
+func (g *GithubNotifier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+
+	var meta struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(payload, &meta); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	repo, ok := g.findRepository(meta.Repository.FullName)
+	if !ok {
+		http.Error(w, "repository is not monitored", http.StatusNotFound)
+		return
+	}
+
+	secret := repo.WebhookSecret
+	if secret == "" {
+		secret = g.config().Webhook.Secret
+	}
+	if secret == "" {
+		http.Error(w, "webhook secret is not configured", http.StatusForbidden)
+		return
+	}
+
+	signature := r.Header.Get(github.SHA256SignatureHeader)
+	if signature == "" {
+		http.Error(w, "missing signature", http.StatusUnauthorized)
+		return
+	}
+	if err := github.ValidateSignature(signature, payload, []byte(secret)); err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	eventType := github.WebHookType(r)
+	if eventType == "ping" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		http.Error(w, "unsupported event", http.StatusBadRequest)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repo":     meta.Repository.FullName,
+		"event":    eventType,
+		"delivery": github.DeliveryID(r),
+	}).Debug("received github webhook")
+
+	g.handleWebhookEvent(repo, event)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (g *GithubNotifier) handleWebhookEvent(repo RepoConfig, event interface{}) {
+	repoPath := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+
+	switch e := event.(type) {
+	case *github.PushEvent:
+		if !repo.monitors("commit") || e.GetDeleted() {
+			return
+		}
+		commits := make([]commitSummary, 0, len(e.Commits))
+		for _, commit := range e.Commits {
+			if !commit.GetDistinct() {
+				continue
+			}
+			commits = append(commits, commitSummary{
+				SHA:     commit.GetID(),
+				Message: commit.GetMessage(),
+				Author:  commit.GetAuthor().GetName(),
+				Time:    commit.GetTimestamp().Time,
+				URL:     commit.GetURL(),
+			})
+		}
+		g.deliverCommits(repoPath, strings.TrimPrefix(e.GetRef(), "refs/heads/"), commits, e.GetCompare())
+
+	case *github.ReleaseEvent:
+		if !repo.monitors("release") || e.GetAction() != "published" {
+			return
+		}
+		release := source.GithubRelease(e.GetRelease())
+		msg := formatReleaseMessage(repoPath,
+			release.Name,
+			release.CreatedAt,
+			release.Body,
+			release.URL)
+		event := NotifyEvent{
+			Repo:   repoPath,
+			Type:   "release",
+			Action: "published",
+			Title:  release.Title(),
+			Author: release.Author,
+			URL:    release.URL,
+			Time:   release.CreatedAt,
+		}
+		g.deliver(event, release.Key, msg, releaseCard(repoPath, release))
+
+	case *github.IssuesEvent:
+		if !repo.monitors("issue") {
+			return
+		}
+		action, ok := webhookActionNames[e.GetAction()]
+		if !ok {
+			return
+		}
+		issue := source.GithubIssue(e.GetIssue())
+		msg := formatIssueMessage(action,
+			repoPath,
+			issue.Title,
+			issue.State,
+			issue.Author,
+			issue.UpdatedAt,
+			issue.URL)
+		event := NotifyEvent{
+			Repo:   repoPath,
+			Type:   "issue",
+			Labels: labelNames(issue.Labels),
+			Action: e.GetAction(),
+			Title:  fmt.Sprintf("#%d %s", issue.Number, issue.Title),
+			Author: issue.Author,
+			URL:    issue.URL,
+			Time:   issue.UpdatedAt,
+		}
+		eventKey := fmt.Sprintf("%d@%d", issue.Number, issue.UpdatedAt.Unix())
+		g.deliver(event, eventKey, msg, issueCard(repoPath, issue))
+
+	case *github.PullRequestEvent:
+		if !repo.monitors("pr") {
+			return
+		}
+		action, ok := webhookActionNames[e.GetAction()]
+		if !ok {
+			return
+		}
+		pr := source.GithubPullRequest(e.GetPullRequest())
+		actionKey := e.GetAction()
+		if actionKey == "closed" && pr.Merged() {
+			action = "合并"
+			actionKey = "merged"
+		}
+		msg := formatPullRequestMessage(action,
+			repoPath,
+			pr.Title,
+			pr.State,
+			pr.Author,
+			pr.UpdatedAt,
+			pr.URL)
+		event := NotifyEvent{
+			Repo:   repoPath,
+			Type:   "pr",
+			Labels: labelNames(pr.Labels),
+			Action: actionKey,
+			Title:  fmt.Sprintf("#%d %s", pr.Number, pr.Title),
+			Author: pr.Author,
+			URL:    pr.URL,
+			Time:   pr.UpdatedAt,
+		}
+		eventKey := fmt.Sprintf("%d@%d", pr.Number, pr.UpdatedAt.Unix())
+		g.deliver(event, eventKey, msg, pullRequestCard(repoPath, pr))
+
+	case *github.WorkflowRunEvent:
+		if !repo.monitors("workflow") || e.GetAction() != "completed" {
+			return
+		}
+		run := source.GithubWorkflowRun(e.GetWorkflowRun())
+		// the same branches as polling, so an empty list means only the default branch
+		if !containsFold(g.workflowBranches(repo), run.Branch) {
+			return
+		}
+		ws, ok := g.sourceFor(repo).(source.WorkflowSource)
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		g.reportWorkflowRun(ctx, ws, repo, run)
+	}
+}
+
+var webhookActionNames = map[string]string{
+	"opened":   "创建",
+	"closed":   "关闭",
+	"reopened": "重新打开",
+}
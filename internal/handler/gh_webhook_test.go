@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"PakuchiBot/internal/repository"
+)
+
+const (
+	testWebhookSecret = "global-secret"
+	testRepoSecret    = "repo-secret"
+)
+
+// newWebhookNotifier returns a notifier without notify targets, delivered events are
+// observed through the digest event log
+func newWebhookNotifier(t *testing.T, apiURL string) (*GithubNotifier, *repository.GithubDigestRepository) {
+	t.Helper()
+
+	db := openTestDB(t)
+	digestRepo := repository.NewGithubDigestRepository(db)
+
+	config := GithubNotifyConfig{
+		Enabled: true,
+		Repositories: []RepoConfig{
+			{
+				Owner:       "octo",
+				Name:        "demo",
+				MonitorType: []string{"commit", "release", "issue", "pr", "workflow"},
+				BaseURL:     apiURL,
+			},
+			{
+				Owner:         "octo",
+				Name:          "private",
+				MonitorType:   []string{"commit"},
+				WebhookSecret: testRepoSecret,
+			},
+		},
+		Webhook: WebhookConfig{Enabled: true, Secret: testWebhookSecret},
+	}
+
+	notifier := NewGithubNotifier(nil, config, nil, repository.NewGithubCursorRepository(db), nil, digestRepo)
+	return notifier, digestRepo
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("testdata", "webhook", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return payload
+}
+
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookRequest(eventType string, payload []byte, signature string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, defaultWebhookPath, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", eventType)
+	req.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	if signature != "" {
+		req.Header.Set("X-Hub-Signature-256", signature)
+	}
+	return req
+}
+
+func TestWebhookSignature(t *testing.T) {
+	demo := readFixture(t, "ping.json")
+	private := bytes.Replace(demo, []byte(`"octo/demo"`), []byte(`"octo/private"`), 1)
+	unknown := bytes.Replace(demo, []byte(`"octo/demo"`), []byte(`"octo/unknown"`), 1)
+
+	tests := []struct {
+		name      string
+		payload   []byte
+		signature string
+		want      int
+	}{
+		{"global secret", demo, sign(testWebhookSecret, demo), http.StatusNoContent},
+		{"wrong secret", demo, sign("not-the-secret", demo), http.StatusUnauthorized},
+		{"tampered payload", demo, sign(testWebhookSecret, private), http.StatusUnauthorized},
+		{"malformed signature", demo, "sha256=zz", http.StatusUnauthorized},
+		{"missing signature", demo, "", http.StatusUnauthorized},
+		{"per-repo secret", private, sign(testRepoSecret, private), http.StatusNoContent},
+		{"global secret on repo with its own", private, sign(testWebhookSecret, private), http.StatusUnauthorized},
+		{"unmonitored repository", unknown, sign(testWebhookSecret, unknown), http.StatusNotFound},
+	}
+
+	notifier, _ := newWebhookNotifier(t, "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			notifier.ServeHTTP(rec, webhookRequest("ping", tt.payload, tt.signature))
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestWebhookRejectsNonPost(t *testing.T) {
+	notifier, _ := newWebhookNotifier(t, "")
+
+	rec := httptest.NewRecorder()
+	notifier.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, defaultWebhookPath, nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+type routedEvent struct {
+	Type   string
+	Action string
+	Title  string
+}
+
+// newWorkflowAPI stands in for the GitHub API the workflow_run handler asks for the default branch and failed jobs
+func newWorkflowAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/repos/octo/demo":
+			w.Write([]byte(`{"full_name": "octo/demo", "default_branch": "main"}`))
+		case "/api/v3/repos/octo/demo/actions/runs/30433642/jobs":
+			w.Write([]byte(`{"total_count": 2, "jobs": [
+				{"id": 1, "name": "lint", "conclusion": "success"},
+				{"id": 2, "name": "test", "conclusion": "failure"}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestWebhookRouting(t *testing.T) {
+	api := newWorkflowAPI(t)
+	defer api.Close()
+
+	tests := []struct {
+		name      string
+		eventType string
+		fixture   string
+		want      []routedEvent
+	}{
+		{
+			name:      "push delivers distinct commits",
+			eventType: "push",
+			fixture:   "push.json",
+			want:      []routedEvent{{"commit", "pushed", "Fix typo in README"}},
+		},
+		{
+			name:      "issue opened",
+			eventType: "issues",
+			fixture:   "issues_opened.json",
+			want:      []routedEvent{{"issue", "opened", "#1347 Found a bug"}},
+		},
+		{
+			name:      "issue labeled is ignored",
+			eventType: "issues",
+			fixture:   "issues_labeled.json",
+			want:      nil,
+		},
+		{
+			name:      "merged pull request",
+			eventType: "pull_request",
+			fixture:   "pull_request_merged.json",
+			want:      []routedEvent{{"pr", "merged", "#42 Add webhook receiver"}},
+		},
+		{
+			name:      "failed workflow run",
+			eventType: "workflow_run",
+			fixture:   "workflow_run_failed.json",
+			want:      []routedEvent{{"workflow", "failed", "CI #562"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, digestRepo := newWebhookNotifier(t, api.URL+"/")
+			payload := readFixture(t, tt.fixture)
+
+			// the second delivery is a redelivery and must not be routed again
+			for i := 0; i < 2; i++ {
+				rec := httptest.NewRecorder()
+				notifier.ServeHTTP(rec, webhookRequest(tt.eventType, payload, sign(testWebhookSecret, payload)))
+				if rec.Code != http.StatusNoContent {
+					t.Fatalf("status = %d, want %d (%s)", rec.Code, http.StatusNoContent, rec.Body.String())
+				}
+			}
+
+			events, err := digestRepo.ListEventsBetween(context.Background(),
+				time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []routedEvent
+			for _, event := range events {
+				if event.Repo != "octo/demo" {
+					t.Errorf("event repo = %q, want octo/demo", event.Repo)
+				}
+				got = append(got, routedEvent{event.EventType, event.Action, event.Title})
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("routed events = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("event %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookWorkflowRunBranches(t *testing.T) {
+	api := newWorkflowAPI(t)
+	defer api.Close()
+
+	payload := readFixture(t, "workflow_run_failed.json")
+	feature := bytes.Replace(payload, []byte(`"head_branch": "main"`), []byte(`"head_branch": "feature/login"`), 1)
+
+	tests := []struct {
+		name     string
+		branches []string
+		payload  []byte
+		want     int
+	}{
+		{"default branch without configured branches", nil, payload, 1},
+		{"other branch without configured branches", nil, feature, 0},
+		{"configured branch", []string{"Feature/Login"}, feature, 1},
+		{"default branch not among configured ones", []string{"release"}, payload, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, digestRepo := newWebhookNotifier(t, api.URL+"/")
+			config := notifier.config()
+			config.Repositories = append([]RepoConfig(nil), config.Repositories...)
+			config.Repositories[0].Branches = tt.branches
+			notifier.applyConfig(config)
+
+			rec := httptest.NewRecorder()
+			notifier.ServeHTTP(rec, webhookRequest("workflow_run", tt.payload, sign(testWebhookSecret, tt.payload)))
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, http.StatusNoContent, rec.Body.String())
+			}
+
+			events, err := digestRepo.ListEventsBetween(context.Background(),
+				time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != tt.want {
+				t.Errorf("reported %d workflow runs, want %d", len(events), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"PakuchiBot/internal/repository"
+	"PakuchiBot/internal/source"
+
+	"github.com/sirupsen/logrus"
+)
+
+// workflowBranches returns the branches whose runs are reported, the default branch when none are configured
+func (g *GithubNotifier) workflowBranches(repo RepoConfig) []string {
+	if len(repo.Branches) > 0 {
+		return repo.Branches
+	}
+	if branch := g.defaultBranch(repo); branch != "" {
+		return []string{branch}
+	}
+	return nil
+}
+
+func (g *GithubNotifier) checkWorkflowRuns(repo RepoConfig, cursor *repository.GithubCursor) {
+	ws, ok := g.sourceFor(repo).(source.WorkflowSource)
+	if !ok {
+		logrus.WithFields(logrus.Fields{
+			"owner":  repo.Owner,
+			"repo":   repo.Name,
+			"source": repo.sourceName(),
+		}).Warn("workflow monitoring is only supported for github repositories")
+		return
+	}
+
+	ctx := context.Background()
+	since := cursor.LastTime
+
+	for _, branch := range g.workflowBranches(repo) {
+		runs, err := ws.WorkflowRuns(ctx, repo.Owner, repo.Name, branch, since)
+		limit, err := splitPageLimit(err)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"owner":  repo.Owner,
+				"repo":   repo.Name,
+				"branch": branch,
+				"error":  err,
+			}).Error("failed to check workflow runs")
+			continue
+		}
+
+		for _, run := range runs {
+			g.reportWorkflowRun(ctx, ws, repo, run)
+
+			if run.UpdatedAt.After(cursor.LastTime) {
+				cursor.LastTime = run.UpdatedAt
+			}
+		}
+
+		g.reportPageLimit(fmt.Sprintf("%s/%s", repo.Owner, repo.Name), cursor.MonitorType, limit)
+	}
+}
+
+// reportWorkflowRun notifies about a failed run, or a successful one that follows a failure when recoveries are enabled
+func (g *GithubNotifier) reportWorkflowRun(ctx context.Context, ws source.WorkflowSource, repo RepoConfig, run source.WorkflowRun) {
+	repoPath := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+
+	var msg, action string
+	switch {
+	case run.Failed():
+		jobs, err := ws.FailedJobs(ctx, repo.Owner, repo.Name, run.ID)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"repo":   repoPath,
+				"run_id": run.ID,
+				"error":  err,
+			}).Warn("failed to list failed workflow jobs")
+		}
+		msg = formatWorkflowFailureMessage(repoPath, run, jobs)
+		action = "failed"
+
+	case run.Conclusion == "success" && repo.NotifyRecovered:
+		previous, err := ws.PreviousRun(ctx, repo.Owner, repo.Name, run)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"repo":   repoPath,
+				"run_id": run.ID,
+				"error":  err,
+			}).Warn("failed to get previous workflow run")
+			return
+		}
+		if previous == nil || !previous.Failed() {
+			return
+		}
+		msg = formatWorkflowRecoveredMessage(repoPath, run)
+		action = "recovered"
+
+	default:
+		return
+	}
+
+	event := NotifyEvent{
+		Repo:   repoPath,
+		Type:   "workflow",
+		Branch: run.Branch,
+		Action: action,
+		Title:  fmt.Sprintf("%s #%d", run.Name, run.RunNumber),
+		Author: run.Actor,
+		URL:    run.URL,
+		Time:   run.UpdatedAt,
+	}
+	g.deliver(event, strconv.FormatInt(run.ID, 10), msg, nil)
+}
+
+func formatWorkflowFailureMessage(repoPath string, run source.WorkflowRun, failedJobs []string) string {
+	jobs := "未知"
+	if len(failedJobs) > 0 {
+		jobs = strings.Join(failedJobs, ", ")
+	}
+
+	return fmt.Sprintf("❌ GitHub Actions 运行失败\n仓库：%s\n工作流：%s #%d\n分支：%s\n提交：%.7s\n触发者：%s\n失败任务：%s\n完成时间：%s\n\n详情：%s",
+		repoPath,
+		run.Name,
+		run.RunNumber,
+		run.Branch,
+		run.HeadSHA,
+		run.Actor,
+		jobs,
+		run.UpdatedAt.Format("2006-01-02 15:04:05"),
+		run.URL)
+}
+
+func formatWorkflowRecoveredMessage(repoPath string, run source.WorkflowRun) string {
+	return fmt.Sprintf("✅ GitHub Actions 已恢复\n仓库：%s\n工作流：%s #%d\n分支：%s\n提交：%.7s\n触发者：%s\n完成时间：%s\n\n详情：%s",
+		repoPath,
+		run.Name,
+		run.RunNumber,
+		run.Branch,
+		run.HeadSHA,
+		run.Actor,
+		run.UpdatedAt.Format("2006-01-02 15:04:05"),
+		run.URL)
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"path/filepath"
+	"testing"
+
+	"PakuchiBot/internal/storage"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// openTestDB opens a migrated database in a temporary directory, closed when the test ends
+func openTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	if err := storage.InitDB(filepath.Join(t.TempDir(), "bot.db")); err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { storage.CloseDB() })
+
+	return storage.GetDB()
+}
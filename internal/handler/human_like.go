@@ -51,34 +51,34 @@ type LLMResponse struct {
 
 func NewHumanLikeHandler() *HumanLikeHandler {
 	return &HumanLikeHandler{
-		bot:            zero.GetBot(bot.Config.Bot.SelfID),
+		bot:            zero.GetBot(bot.Current().Bot.SelfID),
 		messageHistory: make(map[int64][]Message),
 		mutex:          sync.RWMutex{},
 	}
 }
 
 func RegisterHumanLikeHandler() {
-	if !bot.Config.HumanLike.Enabled {
+	if !bot.Current().HumanLike.Enabled {
 		log.Println("HumanLike function is not enabled")
-		return
 	}
 
+	// always registered so that enabling it by reloading the config takes effect
 	handler := NewHumanLikeHandler()
 	handler.Register()
 }
 
 func (h *HumanLikeHandler) Register() {
-	zero.OnMessage().SetBlock(false).
+	newPluginEngine(pluginHumanLike).OnMessage().SetBlock(false).
 		Handle(func(ctx *zero.Ctx) {
-			if ctx.Event.UserID == bot.Config.Bot.SelfID {
+			if !bot.Current().HumanLike.Enabled {
 				return
 			}
 
-			if ctx.Event.GroupID != 0 {
-				if !h.isGroupInWhitelist(ctx.Event.GroupID) {
-					return
-				}
+			if ctx.Event.UserID == bot.Current().Bot.SelfID {
+				return
+			}
 
+			if ctx.Event.GroupID != 0 {
 				h.recordMessage(ctx)
 
 				if h.shouldReply(ctx) {
@@ -88,25 +88,8 @@ func (h *HumanLikeHandler) Register() {
 		})
 }
 
-func (h *HumanLikeHandler) isGroupInWhitelist(groupID int64) bool {
-	if !bot.Config.HumanLike.Behavior.EnableGroupWhitelist {
-		return true
-	}
-
-	if len(bot.Config.HumanLike.Behavior.GroupWhitelist) == 0 {
-		return true
-	}
-
-	for _, id := range bot.Config.HumanLike.Behavior.GroupWhitelist {
-		if id == groupID {
-			return true
-		}
-	}
-	return false
-}
-
 func (h *HumanLikeHandler) recordMessage(ctx *zero.Ctx) {
-	if ctx.Event.UserID == bot.Config.Bot.SelfID {
+	if ctx.Event.UserID == bot.Current().Bot.SelfID {
 		return
 	}
 
@@ -133,10 +116,10 @@ func (h *HumanLikeHandler) recordMessage(ctx *zero.Ctx) {
 			if atCodeRegex.MatchString(msgText) {
 				enhancedText = atCodeRegex.ReplaceAllStringFunc(msgText, func(match string) string {
 					matches := atCodeRegex.FindStringSubmatch(match)
-					if len(matches) >= 2 && matches[1] == strconv.FormatInt(bot.Config.Bot.SelfID, 10) {
+					if len(matches) >= 2 && matches[1] == strconv.FormatInt(bot.Current().Bot.SelfID, 10) {
 						var botName string
-						if len(bot.Config.Bot.NickNames) > 0 {
-							botName = bot.Config.Bot.NickNames[0]
+						if len(bot.Current().Bot.NickNames) > 0 {
+							botName = bot.Current().Bot.NickNames[0]
 						} else {
 							botName = "机器人"
 						}
@@ -146,8 +129,8 @@ func (h *HumanLikeHandler) recordMessage(ctx *zero.Ctx) {
 				})
 			} else {
 				var botName string
-				if len(bot.Config.Bot.NickNames) > 0 {
-					botName = bot.Config.Bot.NickNames[0]
+				if len(bot.Current().Bot.NickNames) > 0 {
+					botName = bot.Current().Bot.NickNames[0]
 				} else {
 					botName = "机器人"
 				}
@@ -174,7 +157,7 @@ func (h *HumanLikeHandler) recordMessage(ctx *zero.Ctx) {
 }
 
 func (h *HumanLikeHandler) shouldReply(ctx *zero.Ctx) bool {
-	if ctx.Event.UserID == bot.Config.Bot.SelfID {
+	if ctx.Event.UserID == bot.Current().Bot.SelfID {
 		return false
 	}
 
@@ -182,7 +165,7 @@ func (h *HumanLikeHandler) shouldReply(ctx *zero.Ctx) bool {
 		return true
 	}
 
-	for _, nickname := range bot.Config.Bot.NickNames {
+	for _, nickname := range bot.Current().Bot.NickNames {
 		if strings.Contains(ctx.Event.RawMessage, nickname) {
 			return rand.Float64() < 0.8
 		}
@@ -325,8 +308,8 @@ func (h *HumanLikeHandler) formatHistoryAsXML(history []Message) string {
 
 		senderName := ""
 		if msg.IsFromBot {
-			if len(bot.Config.Bot.NickNames) > 0 {
-				senderName = bot.Config.Bot.NickNames[0]
+			if len(bot.Current().Bot.NickNames) > 0 {
+				senderName = bot.Current().Bot.NickNames[0]
 			} else {
 				senderName = "Bot"
 			}
@@ -362,10 +345,10 @@ func (h *HumanLikeHandler) cleanXMLText(text string) string {
 
 func (h *HumanLikeHandler) callLLMAPI(apiMessages []APIMessage) (string, error) {
 	requestBody := map[string]interface{}{
-		"model":       bot.Config.HumanLike.LLM.Model,
+		"model":       bot.Current().HumanLike.LLM.Model,
 		"messages":    apiMessages,
-		"temperature": bot.Config.HumanLike.LLM.Temperature,
-		"max_tokens":  bot.Config.HumanLike.LLM.MaxTokens,
+		"temperature": bot.Current().HumanLike.LLM.Temperature,
+		"max_tokens":  bot.Current().HumanLike.LLM.MaxTokens,
 	}
 
 	jsonData, err := json.Marshal(requestBody)
@@ -373,13 +356,13 @@ func (h *HumanLikeHandler) callLLMAPI(apiMessages []APIMessage) (string, error)
 		return "", fmt.Errorf("fail to create request: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", bot.Config.HumanLike.LLM.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", bot.Current().HumanLike.LLM.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("fail to create request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+bot.Config.HumanLike.LLM.APIKey)
+	req.Header.Set("Authorization", "Bearer "+bot.Current().HumanLike.LLM.APIKey)
 
 	client := &http.Client{
 		Timeout: 30 * time.Second,
@@ -422,7 +405,7 @@ func (h *HumanLikeHandler) recordBotReply(groupID int64, content string, msgID i
 	}
 
 	msg := Message{
-		UserID:       bot.Config.Bot.SelfID,
+		UserID:       bot.Current().Bot.SelfID,
 		Content:      content,
 		Timestamp:    time.Now(),
 		IsFromBot:    true,
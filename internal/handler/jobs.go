@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"PakuchiBot/internal/repository"
+	"PakuchiBot/internal/scheduler"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+)
+
+const jobTimeLayout = "2006-01-02 15:04:05"
+
+func RegisterJobsHandler(jobs *scheduler.Scheduler) {
+	zero.OnCommand("jobs", requireRole(roleSuperUser)).Handle(func(ctx *zero.Ctx) {
+		args := strings.Fields(ctx.State["args"].(string))
+		if len(args) == 0 {
+			ctx.Send("请指定操作，例如 /jobs list")
+			return
+		}
+
+		switch args[0] {
+		case "list":
+			handleJobsList(ctx, jobs)
+		case "run":
+			handleJobsRun(ctx, jobs, args[1:])
+		default:
+			ctx.Send("未知操作，支持的操作：list, run")
+		}
+	})
+}
+
+func handleJobsList(ctx *zero.Ctx, jobs *scheduler.Scheduler) {
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	statuses, err := jobs.Statuses(reqCtx)
+	if err != nil {
+		ctx.Send(fmt.Sprintf("获取定时任务时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	loc := jobs.Location()
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("定时任务（时区 %s）：", loc))
+	for _, status := range statuses {
+		sb.WriteString(fmt.Sprintf("\n\n%s %s\n", status.Name, status.Description))
+		sb.WriteString(fmt.Sprintf("调度：%s\n", status.Spec))
+		sb.WriteString(fmt.Sprintf("上次运行：%s\n", formatLastRun(status, loc)))
+
+		if status.NextRun.IsZero() {
+			sb.WriteString("下次运行：无")
+		} else {
+			sb.WriteString(fmt.Sprintf("下次运行：%s", status.NextRun.In(loc).Format(jobTimeLayout)))
+		}
+	}
+
+	ctx.Send(sb.String())
+}
+
+func formatLastRun(status scheduler.JobStatus, loc *time.Location) string {
+	if status.Running {
+		return "运行中"
+	}
+
+	run := status.LastRun
+	if run == nil || run.LastStartedAt == nil {
+		return "从未运行"
+	}
+
+	startedAt := run.LastStartedAt.In(loc).Format(jobTimeLayout)
+	switch run.LastStatus {
+	case repository.JobStatusSuccess:
+		return fmt.Sprintf("%s 成功", startedAt)
+	case repository.JobStatusFailed:
+		return fmt.Sprintf("%s 失败：%s", startedAt, run.LastError)
+	case repository.JobStatusInterrupted:
+		return fmt.Sprintf("%s 被中断", startedAt)
+	default:
+		return startedAt
+	}
+}
+
+func handleJobsRun(ctx *zero.Ctx, jobs *scheduler.Scheduler, args []string) {
+	if len(args) < 1 {
+		ctx.Send("请指定任务名，例如 /jobs run " + scheduler.JobMGClubSign)
+		return
+	}
+	name := args[0]
+
+	if !jobs.Has(name) {
+		ctx.Send(fmt.Sprintf("未知任务 %s，可以使用 /jobs list 查看所有任务", name))
+		return
+	}
+
+	ctx.Send(fmt.Sprintf("开始运行任务 %s", name))
+
+	started := time.Now()
+	err := jobs.RunNow(name)
+	switch {
+	case errors.Is(err, scheduler.ErrJobRunning):
+		ctx.Send(fmt.Sprintf("任务 %s 正在运行中，请稍后再试", name))
+	case err != nil:
+		ctx.Send(fmt.Sprintf("任务 %s 运行失败\n\n%v", name, err))
+	default:
+		ctx.Send(fmt.Sprintf("任务 %s 运行完成，用时 %s", name, time.Since(started).Round(time.Millisecond)))
+	}
+}
@@ -4,6 +4,7 @@ package handler
 
 import (
 	"bufio"
+	"context"
 	_ "embed"
 	"fmt"
 	"hash/fnv"
@@ -12,7 +13,7 @@ import (
 	"strings"
 	"time"
 
-	"PakuchiBot/internal/storage"
+	"PakuchiBot/internal/bot"
 
 	zero "github.com/wdvxdr1123/ZeroBot"
 )
@@ -60,12 +61,15 @@ func init() {
 }
 
 func RegisterLuckHandler() {
-	zero.OnCommand("jrrp").
+	newPluginEngine(pluginLuck).OnCommand("jrrp").
 		Handle(func(ctx *zero.Ctx) {
 			userID := strconv.FormatInt(ctx.Event.UserID, 10)
 			today := time.Now().Format("2006-01-02")
 
-			val, exists, err := storage.GetUserLuck(userID, today)
+			reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			val, exists, err := bot.LuckRepo.Get(reqCtx, userID, today)
 			if err != nil {
 				ctx.Send(fmt.Sprintf("获取今日人品值时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
 				return
@@ -83,7 +87,7 @@ func RegisterLuckHandler() {
 			if !exists {
 				val = generateLuckValue(ctx.Event.UserID, today)
 
-				if err := storage.RecordUserLuck(userID, today, val); err != nil {
+				if err := bot.LuckRepo.Record(reqCtx, userID, today, val); err != nil {
 					ctx.Send(fmt.Sprintf("记录人品值时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
 					return
 				}
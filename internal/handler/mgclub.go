@@ -2,13 +2,16 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	"PakuchiBot/internal/bot"
 	"PakuchiBot/internal/mgclub"
 	"PakuchiBot/internal/repository"
+	"PakuchiBot/internal/scheduler"
 	"PakuchiBot/internal/utils"
 
 	"github.com/sirupsen/logrus"
@@ -16,26 +19,34 @@ import (
 	"github.com/wdvxdr1123/ZeroBot/message"
 )
 
+// mgclubCommandTimeout bounds the MGClub requests behind one chat command
+const mgclubCommandTimeout = time.Minute
+
 type MGClubHandler struct {
 	userRepo   *repository.UserRepository
+	signRepo   *repository.SignRepository
 	notifyRepo *repository.NotifyRepository
 	crypto     *utils.TokenCrypto
 }
 
 func NewMGClubHandler(
 	userRepo *repository.UserRepository,
+	signRepo *repository.SignRepository,
 	notifyRepo *repository.NotifyRepository,
 	crypto *utils.TokenCrypto,
 ) *MGClubHandler {
 	return &MGClubHandler{
 		userRepo:   userRepo,
+		signRepo:   signRepo,
 		notifyRepo: notifyRepo,
 		crypto:     crypto,
 	}
 }
 
 func (h *MGClubHandler) Register() {
-	zero.OnCommand("255token").
+	engine := newPluginEngine(pluginMGClub)
+
+	engine.OnCommand("255token").
 		Handle(func(ctx *zero.Ctx) {
 			args := ctx.State["args"].(string)
 			token := strings.TrimSpace(args)
@@ -77,9 +88,14 @@ func (h *MGClubHandler) Register() {
 			if err := h.notifyRepo.UpsertSetting(reqCtx, userID, groupID); err != nil {
 				log.Printf("failed to save notification settings: %v", err)
 			}
+
+			// today's sign-in may have been given up because of the old token
+			if err := h.signRepo.Resume(reqCtx, userID); err != nil {
+				log.Printf("failed to resume today's sign-in: %v", err)
+			}
 		})
 
-	zero.OnCommand("255sign").
+	engine.OnCommand("255sign").
 		Handle(func(ctx *zero.Ctx) {
 			reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
@@ -99,12 +115,27 @@ func (h *MGClubHandler) Register() {
 				return
 			}
 
-			result, err := mgclub.ProcessSign(token)
+			// interactive requests don't wait behind the slots of a running batch
+			signCtx, signCancel := context.WithTimeout(mgclub.Interactive(context.Background()), mgclubCommandTimeout)
+			defer signCancel()
+
+			result, err := mgclub.ProcessSign(signCtx, token)
 			if err != nil {
+				if errors.Is(err, mgclub.ErrUnauthorized) {
+					ctx.Send("你的毛吧 token 已经失效啦，请使用 " + zero.BotConfig.CommandPrefix + "255token <token值> 重新绑定喵")
+					return
+				}
 				ctx.Send(fmt.Sprintf("签到时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
 				return
 			}
 
+			// signing in can outlast reqCtx, and today's automatic sign-in is no longer needed
+			recordCtx, recordCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer recordCancel()
+			if err := h.signRepo.RecordManual(recordCtx, userID, result.Exp); err != nil {
+				log.Printf("failed to record manual sign-in: %v", err)
+			}
+
 			if result.ImageData != nil {
 				ctx.Send(message.Message{
 					message.Text(result.Message),
@@ -115,7 +146,24 @@ func (h *MGClubHandler) Register() {
 			}
 		})
 
-	zero.OnCommand("255info").Handle(func(ctx *zero.Ctx) {
+	engine.OnCommand("255auto").Handle(h.handleAutoSign)
+	engine.OnCommand("255history").Handle(h.handleHistory)
+	engine.OnCommand("255rank").Handle(h.handleRank)
+
+	zero.OnCommand("rotate-keys", requireRole(roleSuperUser)).Handle(func(ctx *zero.Ctx) {
+		reqCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		rotated, err := h.userRepo.RotateTokens(reqCtx, h.crypto.Reencrypt, h.crypto.NeedsRotation)
+		if err != nil {
+			ctx.Send(fmt.Sprintf("重新加密 token 时出错啦，所有 token 均未改动\n\n%v", err))
+			return
+		}
+
+		ctx.Send(fmt.Sprintf("已使用密钥 %s 重新加密 %d 个 token，确认无误后可以从 old_encryption_keys 中移除旧密钥", h.crypto.CurrentKeyID(), rotated))
+	})
+
+	engine.OnCommand("255info").Handle(func(ctx *zero.Ctx) {
 		reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
@@ -136,7 +184,10 @@ func (h *MGClubHandler) Register() {
 
 		client := mgclub.NewClient()
 
-		info, err := client.GetUserInfo(token)
+		infoCtx, infoCancel := context.WithTimeout(mgclub.Interactive(context.Background()), mgclubCommandTimeout)
+		defer infoCancel()
+
+		info, err := client.GetUserInfo(infoCtx, token)
 		if err != nil {
 			ctx.Send(fmt.Sprintf("获取用户信息时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
 			return
@@ -208,3 +259,95 @@ func (h *MGClubHandler) Register() {
 		}
 	})
 }
+
+// handleAutoSign shows or changes the user's daily auto sign-in window, today's pending
+// sign-in is moved into the new window right away
+func (h *MGClubHandler) handleAutoSign(ctx *zero.Ctx) {
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userID := fmt.Sprintf("%d", ctx.Event.UserID)
+
+	user, err := h.userRepo.GetByUserID(reqCtx, userID)
+	if err != nil {
+		log.Printf("failed to get user by userid:%v, err:%v", userID, err)
+		ctx.Send("你还没有绑定过毛吧账号哦，请先使用 " + zero.BotConfig.CommandPrefix + "255token <token值> 绑定你的毛吧账号")
+		return
+	}
+
+	defaultWindow := bot.Current().DefaultSignWindow()
+	arg := strings.TrimSpace(ctx.State["args"].(string))
+
+	if arg == "" {
+		ctx.Send(h.autoSignStatus(reqCtx, user, defaultWindow))
+		return
+	}
+
+	window := ""
+	if arg != "off" {
+		parsed, err := scheduler.ParseSignWindow(arg)
+		if err != nil {
+			ctx.Send("时间段格式不对哦，请使用 " + zero.BotConfig.CommandPrefix + "255auto 08:00-09:00 这样的格式，结束时间需要晚于开始时间\n\n取消自定义时间段请使用 " + zero.BotConfig.CommandPrefix + "255auto off")
+			return
+		}
+		window = parsed.String()
+	}
+
+	if err := h.userRepo.SetSignWindow(reqCtx, userID, window); err != nil {
+		ctx.Send(fmt.Sprintf("保存签到时间段时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	scheduledAt, err := scheduler.ScheduleFor(window, defaultWindow, time.Now(), bot.Current().Location())
+	if err != nil {
+		ctx.Send(fmt.Sprintf("计算签到时间时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+	if err := h.signRepo.Reschedule(reqCtx, userID, scheduledAt); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err,
+		}).Error("failed to reschedule today's sign-in")
+	}
+
+	user.SignWindow = window
+	prefix := "已取消自定义签到时间段喵"
+	if window != "" {
+		prefix = fmt.Sprintf("已将每日自动签到时间段设置为 %s 喵", window)
+	}
+	ctx.Send(prefix + "\n\n" + h.autoSignStatus(reqCtx, user, defaultWindow))
+}
+
+func (h *MGClubHandler) autoSignStatus(ctx context.Context, user *repository.User, defaultWindow scheduler.SignWindow) string {
+	var sb strings.Builder
+
+	switch {
+	case user.SignWindow != "":
+		sb.WriteString(fmt.Sprintf("每日自动签到时间段：%s", user.SignWindow))
+	case !defaultWindow.IsZero():
+		sb.WriteString(fmt.Sprintf("每日自动签到时间段：%s（默认）", defaultWindow))
+	default:
+		sb.WriteString("每日自动签到时间段：未设置，每天会尽快签到")
+	}
+
+	record, err := h.signRepo.GetTodayRecord(ctx, user.UserID)
+	switch {
+	case err != nil:
+		sb.WriteString("\n今天的签到时间还没有安排")
+	case record.Status == repository.SignStatusSuccess:
+		sb.WriteString("\n今天已经签到过啦")
+	case record.Status == repository.SignStatusAbandoned && record.FailureReason == repository.SignFailureAuth:
+		sb.WriteString("\n你的 token 已经失效，自动签到已暂停，请重新绑定 token")
+	case record.Status == repository.SignStatusAbandoned,
+		record.Status == repository.SignStatusFailed && record.RetryCount >= bot.Current().Scheduler.MaxRetries:
+		sb.WriteString("\n今天的自动签到失败了，不会再重试")
+	case record.Status == repository.SignStatusFailed && record.NextRetryAt != nil:
+		sb.WriteString(fmt.Sprintf("\n今天的签到失败了，将在 %s 重试", record.NextRetryAt.Format("15:04:05")))
+	case record.ScheduledAt != nil:
+		sb.WriteString(fmt.Sprintf("\n今天预计在 %s 签到", record.ScheduledAt.Format("15:04:05")))
+	default:
+		sb.WriteString("\n今天会尽快签到")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"PakuchiBot/internal/bot"
+	"PakuchiBot/internal/repository"
+	"PakuchiBot/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+const (
+	defaultHistoryDays = 90
+	minHistoryDays     = 7
+	maxHistoryDays     = 365
+	rankSize           = 10
+	signDateLayout     = "2006-01-02"
+)
+
+// handleHistory draws the user's sign-in heatmap for the last days together with streaks and EXP
+func (h *MGClubHandler) handleHistory(ctx *zero.Ctx) {
+	days := defaultHistoryDays
+	if arg := strings.TrimSpace(ctx.State["args"].(string)); arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < minHistoryDays || n > maxHistoryDays {
+			ctx.Send(fmt.Sprintf("天数需要在 %d 到 %d 之间哦，例如 %s255history 30", minHistoryDays, maxHistoryDays, zero.BotConfig.CommandPrefix))
+			return
+		}
+		days = n
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userID := fmt.Sprintf("%d", ctx.Event.UserID)
+
+	if _, err := h.userRepo.GetByUserID(reqCtx, userID); err != nil {
+		log.Printf("failed to get user by userid:%v, err:%v", userID, err)
+		ctx.Send("你还没有绑定过毛吧账号哦，请先使用 " + zero.BotConfig.CommandPrefix + "255token <token值> 绑定你的毛吧账号")
+		return
+	}
+
+	history, err := h.signRepo.GetHistory(reqCtx, userID)
+	if err != nil {
+		ctx.Send(fmt.Sprintf("获取签到记录时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	today := signDate(time.Now().In(bot.Current().Location()))
+	start := today.AddDate(0, 0, -(days - 1))
+
+	var successDates []string
+	var totalExp, signedDays, manualDays, failedDays int
+	levels := make(map[string]utils.SignHeatmapLevel)
+	for _, day := range history {
+		date := day.SignDate.Format(signDateLayout)
+		totalExp += day.Exp
+		if day.Status == repository.SignStatusSuccess {
+			successDates = append(successDates, date)
+		}
+
+		if day.SignDate.Before(start) {
+			continue
+		}
+		switch {
+		case day.Status == repository.SignStatusSuccess && day.Manual:
+			levels[date] = utils.SignHeatmapManual
+			signedDays++
+			manualDays++
+		case day.Status == repository.SignStatusSuccess:
+			levels[date] = utils.SignHeatmapSuccess
+			signedDays++
+		case day.Status == repository.SignStatusFailed, day.Status == repository.SignStatusAbandoned:
+			levels[date] = utils.SignHeatmapFailed
+			failedDays++
+		}
+	}
+
+	current, longest := signStreaks(successDates, today)
+	stats := []string{
+		fmt.Sprintf("当前连续签到 %d 天，最长连续签到 %d 天", current, longest),
+		fmt.Sprintf("近 %d 天签到 %d 天（手动 %d 天），失败 %d 天", days, signedDays, manualDays, failedDays),
+		fmt.Sprintf("累计获得经验 %d", totalExp),
+	}
+
+	image, err := utils.GenerateSignHeatmap(utils.SignHeatmap{
+		Title: fmt.Sprintf("%s 的签到记录", ctx.Event.Sender.Name()),
+		Start: start,
+		End:   today,
+		Days:  levels,
+		Stats: stats,
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err,
+		}).Error("failed to generate sign-in heatmap")
+
+		ctx.Send(strings.Join(stats, "\n"))
+		return
+	}
+
+	ctx.Send(message.Message{
+		message.ImageBytes(image),
+	})
+}
+
+type streakEntry struct {
+	userID  int64
+	name    string
+	current int
+	longest int
+}
+
+// handleRank ranks the bound members of the group by their current streak
+func (h *MGClubHandler) handleRank(ctx *zero.Ctx) {
+	if ctx.Event.GroupID == 0 {
+		ctx.Send("请在群聊中使用")
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	members := ctx.GetThisGroupMemberList().Array()
+	memberIDs := make([]string, len(members))
+	for i, member := range members {
+		memberIDs[i] = member.Get("user_id").String()
+	}
+
+	successDays, err := h.signRepo.GetSuccessDays(reqCtx, memberIDs)
+	if err != nil {
+		ctx.Send(fmt.Sprintf("获取签到记录时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	datesByUser := make(map[string][]string)
+	for _, day := range successDays {
+		datesByUser[day.UserID] = append(datesByUser[day.UserID], day.SignDate.Format(signDateLayout))
+	}
+
+	today := signDate(time.Now().In(bot.Current().Location()))
+	var entries []streakEntry
+	for _, member := range members {
+		userID := member.Get("user_id").Int()
+		dates, ok := datesByUser[strconv.FormatInt(userID, 10)]
+		if !ok {
+			continue
+		}
+
+		name := member.Get("card").String()
+		if name == "" {
+			name = member.Get("nickname").String()
+		}
+
+		current, longest := signStreaks(dates, today)
+		entries = append(entries, streakEntry{userID: userID, name: name, current: current, longest: longest})
+	}
+
+	if len(entries) == 0 {
+		ctx.Send("本群还没有人签到过喵，使用 " + zero.BotConfig.CommandPrefix + "255token <token值> 绑定毛吧账号后就会每天自动签到")
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].current != entries[j].current {
+			return entries[i].current > entries[j].current
+		}
+		if entries[i].longest != entries[j].longest {
+			return entries[i].longest > entries[j].longest
+		}
+		return entries[i].userID < entries[j].userID
+	})
+
+	var sb strings.Builder
+	sb.WriteString("本群连续签到排行：")
+	for i, entry := range entries {
+		if i < rankSize {
+			sb.WriteString(fmt.Sprintf("\n%d. %s 连续 %d 天（最长 %d 天）", i+1, entry.name, entry.current, entry.longest))
+			continue
+		}
+		if entry.userID == ctx.Event.UserID {
+			sb.WriteString(fmt.Sprintf("\n\n你排在第 %d 名，连续 %d 天（最长 %d 天）", i+1, entry.current, entry.longest))
+			break
+		}
+	}
+
+	ctx.Send(sb.String())
+}
+
+// signStreaks takes ascending successful sign-in dates. Today's sign-in may not have happened
+// yet, so the current streak also counts when it ended yesterday.
+func signStreaks(dates []string, today time.Time) (current, longest int) {
+	signed := make(map[string]bool, len(dates))
+	run := 0
+	var prev time.Time
+	for _, date := range dates {
+		day, err := time.Parse(signDateLayout, date)
+		if err != nil {
+			continue
+		}
+		signed[date] = true
+
+		if !prev.IsZero() && prev.AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		prev = day
+		longest = max(longest, run)
+	}
+
+	day := today
+	if !signed[day.Format(signDateLayout)] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for signed[day.Format(signDateLayout)] {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return current, longest
+}
+
+// signDate returns the calendar day of t in its own zone as midnight UTC, the way sign_date is read back
+func signDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignStreaks(t *testing.T) {
+	today := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		dates       []string
+		wantCurrent int
+		wantLongest int
+	}{
+		{
+			name: "no sign-ins",
+		},
+		{
+			name:        "single day today",
+			dates:       []string{"2026-10-17"},
+			wantCurrent: 1,
+			wantLongest: 1,
+		},
+		{
+			name:        "single day long ago",
+			dates:       []string{"2026-09-01"},
+			wantCurrent: 0,
+			wantLongest: 1,
+		},
+		{
+			name:        "today not signed yet keeps yesterday's streak",
+			dates:       []string{"2026-10-14", "2026-10-15", "2026-10-16"},
+			wantCurrent: 3,
+			wantLongest: 3,
+		},
+		{
+			name:        "missed yesterday breaks the streak",
+			dates:       []string{"2026-10-13", "2026-10-14", "2026-10-15"},
+			wantCurrent: 0,
+			wantLongest: 3,
+		},
+		{
+			name:        "signed today after a gap",
+			dates:       []string{"2026-10-10", "2026-10-11", "2026-10-12", "2026-10-13", "2026-10-16", "2026-10-17"},
+			wantCurrent: 2,
+			wantLongest: 4,
+		},
+		{
+			name:        "longest run in the past",
+			dates:       []string{"2026-08-01", "2026-08-02", "2026-08-03", "2026-08-04", "2026-08-05", "2026-10-17"},
+			wantCurrent: 1,
+			wantLongest: 5,
+		},
+		{
+			name:        "across a month end",
+			dates:       []string{"2026-09-29", "2026-09-30", "2026-10-01"},
+			wantCurrent: 0,
+			wantLongest: 3,
+		},
+		{
+			name:        "unparsable dates are skipped",
+			dates:       []string{"2026-10-16", "not a date", "2026-10-17"},
+			wantCurrent: 2,
+			wantLongest: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, longest := signStreaks(tt.dates, today)
+			if current != tt.wantCurrent || longest != tt.wantLongest {
+				t.Errorf("signStreaks() = (%d, %d), want (%d, %d)", current, longest, tt.wantCurrent, tt.wantLongest)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"fmt"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+)
+
+// role is what a user may do, higher roles include the lower ones
+type role int
+
+const (
+	roleMember role = iota
+	// roleGroupAdmin is the group owner or an admin, in private chats users manage their own
+	// notifications so this is granted to everyone there
+	roleGroupAdmin
+	// roleSuperUser is listed in bot.super_users
+	roleSuperUser
+)
+
+func (r role) String() string {
+	switch r {
+	case roleGroupAdmin:
+		return "群主或管理员"
+	case roleSuperUser:
+		return "超级用户"
+	default:
+		return "群成员"
+	}
+}
+
+// roleOf derives the sender's role from bot.super_users and the OneBot group member role
+func roleOf(ctx *zero.Ctx) role {
+	if zero.SuperUserPermission(ctx) {
+		return roleSuperUser
+	}
+
+	if ctx.Event.GroupID != 0 && ctx.Event.Sender != nil {
+		switch ctx.Event.Sender.Role {
+		case "owner", "admin":
+			return roleGroupAdmin
+		}
+	}
+
+	return roleMember
+}
+
+func hasRole(ctx *zero.Ctx, required role) bool {
+	if required == roleGroupAdmin && ctx.Event.GroupID == 0 {
+		return true
+	}
+	return roleOf(ctx) >= required
+}
+
+// checkRole replies with a refusal when the sender lacks the required role
+func checkRole(ctx *zero.Ctx, required role) bool {
+	if hasRole(ctx, required) {
+		return true
+	}
+
+	ctx.Send(fmt.Sprintf("抱歉，这个操作需要%s权限哦", required))
+	return false
+}
+
+// requireRole is a matcher rule for commands that need a role as a whole
+func requireRole(required role) zero.Rule {
+	return func(ctx *zero.Ctx) bool {
+		return checkRole(ctx, required)
+	}
+}
@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"PakuchiBot/internal/bot"
+	"PakuchiBot/internal/repository"
+
+	"github.com/sirupsen/logrus"
+	zero "github.com/wdvxdr1123/ZeroBot"
+)
+
+const (
+	pluginLuck      = "jrrp"
+	pluginMGClub    = "mgclub"
+	pluginGithub    = "github"
+	pluginHumanLike = "humanlike"
+)
+
+type pluginInfo struct {
+	Name        string
+	Description string
+}
+
+// managedPlugins can be switched on and off per group, ping and /plugin itself are always available
+var managedPlugins = []pluginInfo{
+	{Name: pluginLuck, Description: "今日人品"},
+	{Name: pluginMGClub, Description: "255 签到"},
+	{Name: pluginGithub, Description: "GitHub 通知与查询"},
+	{Name: pluginHumanLike, Description: "拟人聊天"},
+}
+
+// pluginManager caches plugin_settings, the rule runs for every message so it must not hit the database
+type pluginManager struct {
+	repo *repository.PluginRepository
+
+	mu       sync.RWMutex
+	settings map[int64]map[string]bool // group ID -> plugin -> enabled, repository.GlobalPluginGroup holds the defaults
+}
+
+var plugins = &pluginManager{settings: make(map[int64]map[string]bool)}
+
+// newPluginEngine returns an engine whose handlers only run in groups where the plugin is enabled
+func newPluginEngine(name string) *zero.Engine {
+	engine := zero.New()
+	engine.UsePreHandler(plugins.rule(name))
+	return engine
+}
+
+func (m *pluginManager) rule(name string) zero.Rule {
+	return func(ctx *zero.Ctx) bool {
+		if ctx.Event.GroupID == 0 {
+			return true
+		}
+		return m.enabled(ctx.Event.GroupID, name)
+	}
+}
+
+// enabled resolves a group setting, then the global default, plugins are on when neither is set
+func (m *pluginManager) enabled(groupID int64, name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if enabled, ok := m.settings[groupID][name]; ok {
+		return enabled
+	}
+	if enabled, ok := m.settings[repository.GlobalPluginGroup][name]; ok {
+		return enabled
+	}
+	return true
+}
+
+// setting returns the stored value for a group without falling back
+func (m *pluginManager) setting(groupID int64, name string) (bool, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	enabled, ok := m.settings[groupID][name]
+	return enabled, ok
+}
+
+func (m *pluginManager) load(ctx context.Context) error {
+	settings, err := m.repo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.settings = make(map[int64]map[string]bool)
+	for _, s := range settings {
+		m.cache(s.GroupID, s.Plugin, s.Enabled)
+	}
+	return nil
+}
+
+// cache must be called with mu held
+func (m *pluginManager) cache(groupID int64, name string, enabled bool) {
+	if m.settings[groupID] == nil {
+		m.settings[groupID] = make(map[string]bool)
+	}
+	m.settings[groupID][name] = enabled
+}
+
+func (m *pluginManager) set(ctx context.Context, groupID int64, name string, enabled bool, updatedBy int64) error {
+	if err := m.repo.Set(ctx, groupID, name, enabled, updatedBy); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.cache(groupID, name, enabled)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *pluginManager) reset(ctx context.Context, groupID int64, name string) error {
+	if err := m.repo.Delete(ctx, groupID, name); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	delete(m.settings[groupID], name)
+	m.mu.Unlock()
+	return nil
+}
+
+// importHumanLikeWhitelist turns the deprecated HumanLike group whitelist into plugin settings,
+// only while no humanlike setting exists so it runs once
+func (m *pluginManager) importHumanLikeWhitelist(ctx context.Context) error {
+	behavior := bot.Config.HumanLike.Behavior
+	if !behavior.EnableGroupWhitelist || len(behavior.GroupWhitelist) == 0 {
+		return nil
+	}
+
+	m.mu.RLock()
+	for _, groups := range m.settings {
+		if _, ok := groups[pluginHumanLike]; ok {
+			m.mu.RUnlock()
+			return nil
+		}
+	}
+	m.mu.RUnlock()
+
+	if err := m.set(ctx, repository.GlobalPluginGroup, pluginHumanLike, false, bot.Config.Bot.SelfID); err != nil {
+		return err
+	}
+	for _, groupID := range behavior.GroupWhitelist {
+		if err := m.set(ctx, groupID, pluginHumanLike, true, bot.Config.Bot.SelfID); err != nil {
+			return err
+		}
+	}
+
+	logrus.WithField("groups", behavior.GroupWhitelist).
+		Warn("humanlike.behavior.group_whitelist is deprecated and has been imported into plugin settings, use /plugin instead")
+	return nil
+}
+
+func findPlugin(name string) (pluginInfo, bool) {
+	for _, p := range managedPlugins {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return pluginInfo{}, false
+}
+
+func pluginNames() string {
+	names := make([]string, len(managedPlugins))
+	for i, p := range managedPlugins {
+		names[i] = p.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// pluginCommandRoles annotates /plugin subcommands with the role they require
+var pluginCommandRoles = map[string]role{
+	"on":      roleGroupAdmin,
+	"off":     roleGroupAdmin,
+	"reset":   roleGroupAdmin,
+	"default": roleSuperUser,
+}
+
+func RegisterPluginHandler() {
+	plugins.repo = bot.PluginRepo
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := plugins.load(ctx); err != nil {
+		logrus.WithError(err).Error("failed to load plugin settings")
+	}
+	if err := plugins.importHumanLikeWhitelist(ctx); err != nil {
+		logrus.WithError(err).Error("failed to import humanlike group whitelist")
+	}
+
+	zero.OnCommand("plugin").Handle(func(ctx *zero.Ctx) {
+		args := strings.Fields(ctx.State["args"].(string))
+		if len(args) == 0 {
+			ctx.Send("请指定操作，例如 /plugin list")
+			return
+		}
+
+		if !checkRole(ctx, pluginCommandRoles[args[0]]) {
+			return
+		}
+
+		switch args[0] {
+		case "list":
+			handlePluginList(ctx)
+		case "on", "off":
+			handlePluginSwitch(ctx, args[0] == "on", args[1:])
+		case "reset":
+			handlePluginReset(ctx, args[1:])
+		case "default":
+			handlePluginDefault(ctx, args[1:])
+		default:
+			ctx.Send("未知操作，支持的操作：list, on, off, reset, default")
+		}
+	})
+}
+
+func handlePluginList(ctx *zero.Ctx) {
+	groupID := ctx.Event.GroupID
+
+	var sb strings.Builder
+	if groupID == 0 {
+		sb.WriteString("插件全局默认状态：\n")
+	} else {
+		sb.WriteString("本群插件状态：\n")
+	}
+
+	for _, p := range managedPlugins {
+		status := "关闭"
+		if plugins.enabled(groupID, p.Name) {
+			status = "开启"
+		}
+
+		origin := ""
+		if groupID != 0 {
+			origin = "（全局默认）"
+			if _, ok := plugins.setting(groupID, p.Name); ok {
+				origin = "（本群设置）"
+			}
+		}
+
+		sb.WriteString(fmt.Sprintf("%s %s：%s%s\n", p.Name, p.Description, status, origin))
+	}
+
+	ctx.Send(strings.TrimSuffix(sb.String(), "\n"))
+}
+
+func handlePluginSwitch(ctx *zero.Ctx, enabled bool, args []string) {
+	if ctx.Event.GroupID == 0 {
+		ctx.Send("请在群聊中使用，修改全局默认请使用 /plugin default on|off <插件名>")
+		return
+	}
+
+	p, ok := parsePluginArg(ctx, args)
+	if !ok {
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := plugins.set(reqCtx, ctx.Event.GroupID, p.Name, enabled, ctx.Event.UserID); err != nil {
+		ctx.Send(fmt.Sprintf("修改插件开关时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	if enabled {
+		ctx.Send(fmt.Sprintf("已在本群开启插件 %s", p.Name))
+	} else {
+		ctx.Send(fmt.Sprintf("已在本群关闭插件 %s", p.Name))
+	}
+}
+
+func handlePluginReset(ctx *zero.Ctx, args []string) {
+	if ctx.Event.GroupID == 0 {
+		ctx.Send("请在群聊中使用")
+		return
+	}
+
+	p, ok := parsePluginArg(ctx, args)
+	if !ok {
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := plugins.reset(reqCtx, ctx.Event.GroupID, p.Name); err != nil {
+		if errors.Is(err, repository.ErrPluginSettingNotFound) {
+			ctx.Send(fmt.Sprintf("本群没有单独设置插件 %s", p.Name))
+			return
+		}
+		ctx.Send(fmt.Sprintf("重置插件开关时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	ctx.Send(fmt.Sprintf("插件 %s 已恢复为全局默认设置", p.Name))
+}
+
+func handlePluginDefault(ctx *zero.Ctx, args []string) {
+	if len(args) < 1 || (args[0] != "on" && args[0] != "off") {
+		ctx.Send("用法：/plugin default on|off <插件名>")
+		return
+	}
+	enabled := args[0] == "on"
+
+	p, ok := parsePluginArg(ctx, args[1:])
+	if !ok {
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := plugins.set(reqCtx, repository.GlobalPluginGroup, p.Name, enabled, ctx.Event.UserID); err != nil {
+		ctx.Send(fmt.Sprintf("修改插件默认设置时出错啦，请将错误信息反馈给管理员哦\n\n%v", err))
+		return
+	}
+
+	if enabled {
+		ctx.Send(fmt.Sprintf("插件 %s 已默认开启，单独设置过的群不受影响", p.Name))
+	} else {
+		ctx.Send(fmt.Sprintf("插件 %s 已默认关闭，单独设置过的群不受影响", p.Name))
+	}
+}
+
+func parsePluginArg(ctx *zero.Ctx, args []string) (pluginInfo, bool) {
+	if len(args) < 1 {
+		ctx.Send(fmt.Sprintf("请指定插件名，可选：%s", pluginNames()))
+		return pluginInfo{}, false
+	}
+
+	p, ok := findPlugin(args[0])
+	if !ok {
+		ctx.Send(fmt.Sprintf("未知插件 %s，可选：%s", args[0], pluginNames()))
+		return pluginInfo{}, false
+	}
+	return p, true
+}
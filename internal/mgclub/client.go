@@ -1,6 +1,7 @@
 package mgclub
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,7 +15,7 @@ type Client struct {
 
 func NewClient() *Client {
 	return &Client{
-		httpClient: &http.Client{},
+		httpClient: httpClient,
 	}
 }
 
@@ -35,8 +36,8 @@ type UserInfo struct {
 	Birthday       *int    `json:"birthday"`
 }
 
-func (c *Client) GetUserInfo(token string) (*UserInfo, error) {
-	req, err := http.NewRequest("GET", "https://2550505.com/user/info", nil)
+func (c *Client) GetUserInfo(ctx context.Context, token string) (*UserInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://2550505.com/user/info", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
@@ -48,28 +49,31 @@ func (c *Client) GetUserInfo(token string) (*UserInfo, error) {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+		return nil, fmt.Errorf("%w: failed to send request: %w", ErrNetwork, err)
 	}
 	defer resp.Body.Close()
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return nil, fmt.Errorf("%w: failed to read response body: %w", ErrNetwork, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp.StatusCode, bodyBytes)
 	}
 
 	var result struct {
 		Code int      `json:"code"`
+		Msg  string   `json:"msg"`
 		Info UserInfo `json:"info"`
 	}
 
 	if err := json.Unmarshal(bodyBytes, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: HTTP status code %d, response body: %s, error: %v",
-			resp.StatusCode, string(bodyBytes), err)
+		return nil, fmt.Errorf("%w: failed to parse response: %w, response body: %s", ErrBadResponse, err, string(bodyBytes))
 	}
 
 	if result.Code != 0 {
-		return nil, fmt.Errorf("API returned error code: %d, HTTP status code: %d, response body: %s",
-			result.Code, resp.StatusCode, string(bodyBytes))
+		return nil, codeError(result.Code, result.Msg, bodyBytes)
 	}
 
 	return &result.Info, nil
@@ -0,0 +1,53 @@
+package mgclub
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// errors returned by the API calls can be matched with errors.Is to decide whether retrying helps
+var (
+	// ErrNetwork means the request didn't complete, e.g. a timeout or a refused connection
+	ErrNetwork = errors.New("network error")
+	// ErrServer means the server answered with a 5xx status or asked to slow down
+	ErrServer = errors.New("server error")
+	// ErrUnauthorized means the token was rejected and has to be bound again
+	ErrUnauthorized = errors.New("token is invalid or expired")
+	// ErrRejected means the server refused the request with a 4xx status or an error code in the
+	// response, asking again won't change the answer
+	ErrRejected = errors.New("request rejected")
+	// ErrBadResponse means the response could not be decoded
+	ErrBadResponse = errors.New("unexpected response")
+)
+
+// unauthorizedCodes are the error codes MGClub answers with, next to HTTP 200, when the token is not logged in
+var unauthorizedCodes = map[int]bool{
+	401: true,
+	403: true,
+}
+
+// statusError classifies an unexpected HTTP status
+func statusError(statusCode int, body []byte) error {
+	message := fmt.Sprintf("server returned non-200 status code: %d, response: %s", statusCode, string(body))
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: %s", ErrUnauthorized, message)
+	case statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s", ErrServer, message)
+	default:
+		return fmt.Errorf("%w: %s", ErrRejected, message)
+	}
+}
+
+// codeError classifies a non-zero code in a response that came with HTTP 200
+func codeError(code int, msg string, body []byte) error {
+	message := fmt.Sprintf("server returned error code %d, response: %s", code, string(body))
+
+	if unauthorizedCodes[code] || strings.Contains(msg, "登录") {
+		return fmt.Errorf("%w: %s", ErrUnauthorized, message)
+	}
+	return fmt.Errorf("%w: %s", ErrRejected, message)
+}
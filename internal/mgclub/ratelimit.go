@@ -0,0 +1,150 @@
+package mgclub
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// requestTimeout bounds a single request from the moment its rate limit slot comes, the wait for
+// the slot is only bounded by the caller's context. With sign_concurrency workers sharing
+// sign_rate_limit slots per second a request may queue for concurrency/rate seconds first.
+const requestTimeout = 30 * time.Second
+
+// httpClient is shared by every request to MGClub so concurrent sign-ins respect one rate limit
+var httpClient = &http.Client{
+	Transport: &rateLimitedTransport{
+		base:        http.DefaultTransport,
+		batch:       batchLimiter,
+		interactive: interactiveLimiter,
+	},
+}
+
+var (
+	batchLimiter = &hostLimiter{next: make(map[string]time.Time)}
+	// chat commands get slots of their own so they don't queue behind the scheduled sign-ins
+	interactiveLimiter = &hostLimiter{next: make(map[string]time.Time)}
+)
+
+type interactiveKey struct{}
+
+// Interactive marks requests made with the returned context as answering a chat command. They are
+// spaced apart on their own instead of waiting for the slots reserved by a running batch.
+func Interactive(ctx context.Context) context.Context {
+	return context.WithValue(ctx, interactiveKey{}, true)
+}
+
+func isInteractive(ctx context.Context) bool {
+	interactive, _ := ctx.Value(interactiveKey{}).(bool)
+	return interactive
+}
+
+// SetRateLimit limits requests to perSecond per host for the batch and for chat commands each, 0 removes the limit
+func SetRateLimit(perSecond float64) {
+	var interval time.Duration
+	if perSecond > 0 {
+		interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	batchLimiter.setInterval(interval)
+	interactiveLimiter.setInterval(interval)
+}
+
+// hostLimiter spaces requests to the same host at least interval apart
+type hostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time // host -> earliest time the next request may start
+}
+
+func (l *hostLimiter) setInterval(interval time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.interval = interval
+}
+
+// wait reserves the next free slot for host and sleeps until it comes, a caller that gives up
+// first hands its slot back unless later requests already queued behind it
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	if l.interval == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	reserved := at.Add(l.interval)
+	l.next[host] = reserved
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		l.release(host, at, reserved)
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (l *hostLimiter) release(host string, at, reserved time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.next[host].Equal(reserved) {
+		l.next[host] = at
+	}
+}
+
+type rateLimitedTransport struct {
+	base        http.RoundTripper
+	batch       *hostLimiter
+	interactive *hostLimiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limiter := t.batch
+	if isInteractive(req.Context()) {
+		limiter = t.interactive
+	}
+	if err := limiter.wait(req.Context(), req.URL.Host); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), requestTimeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose keeps the request timeout running while the body is read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -2,6 +2,7 @@ package mgclub
 
 import (
 	"PakuchiBot/internal/utils"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -25,38 +26,40 @@ type SignDaysResponse struct {
 type SignResult struct {
 	IsSuccess   bool
 	AlreadyDone bool
+	Exp         int
 	Message     string
 	ImageURL    string
 	ImageData   []byte
 }
 
-func ProcessSign(token string) (SignResult, error) {
+// ProcessSign signs in with token and prepares the reply, ctx bounds every request it makes
+func ProcessSign(ctx context.Context, token string) (SignResult, error) {
 	result := SignResult{
 		ImageURL: "https://cdn.2550505.com/share/assets/mgclub/editor/sign-1.png",
 	}
 
 	client := NewClient()
-	userInfo, err := client.GetUserInfo(token)
+	userInfo, err := client.GetUserInfo(ctx, token)
 	if err != nil {
 		log.Printf("Failed to get user info: %v", err)
 	}
 
-	signResp, err := DoSign(token)
+	signResp, err := DoSign(ctx, token)
 	if err != nil {
 		return result, fmt.Errorf("failed to sign in: %w", err)
 	}
 
 	if signResp == nil {
 		result.AlreadyDone = true
-		daysResp, err := GetSignDays(token)
+		daysResp, err := GetSignDays(ctx, token)
 		if err != nil {
 			result.Message = "你今天已经签到过了喵～（获取签到天数失败）"
 			result.IsSuccess = true
 
 			if userInfo != nil {
-				downloadSignCardWithUserInfo(&result, userInfo, 0)
+				downloadSignCardWithUserInfo(ctx, &result, userInfo, 0)
 			} else {
-				downloadImage(&result)
+				downloadImage(ctx, &result)
 			}
 
 			return result, nil
@@ -65,23 +68,24 @@ func ProcessSign(token string) (SignResult, error) {
 		result.IsSuccess = true
 
 		if userInfo != nil {
-			downloadSignCardWithUserInfo(&result, userInfo, daysResp.Day)
+			downloadSignCardWithUserInfo(ctx, &result, userInfo, daysResp.Day)
 		} else {
-			downloadImage(&result)
+			downloadImage(ctx, &result)
 		}
 
 		return result, nil
 	}
 
 	result.IsSuccess = true
-	daysResp, err := GetSignDays(token)
+	result.Exp = signResp.Exp
+	daysResp, err := GetSignDays(ctx, token)
 	if err != nil {
 		result.Message = fmt.Sprintf("签到成功喵\n获得经验：%d\n（获取签到天数失败：%v）", signResp.Exp, err)
 
 		if userInfo != nil {
-			downloadSignCardWithUserInfo(&result, userInfo, 0)
+			downloadSignCardWithUserInfo(ctx, &result, userInfo, 0)
 		} else {
-			downloadImage(&result)
+			downloadImage(ctx, &result)
 		}
 
 		return result, nil
@@ -96,20 +100,20 @@ func ProcessSign(token string) (SignResult, error) {
 	}
 
 	if userInfo != nil {
-		downloadSignCardWithUserInfo(&result, userInfo, daysResp.Day)
+		downloadSignCardWithUserInfo(ctx, &result, userInfo, daysResp.Day)
 	} else {
-		downloadImage(&result)
+		downloadImage(ctx, &result)
 	}
 
 	return result, nil
 }
 
-func downloadSignCardWithUserInfo(result *SignResult, userInfo *UserInfo, signDays int) {
+func downloadSignCardWithUserInfo(ctx context.Context, result *SignResult, userInfo *UserInfo, signDays int) {
 	if result.ImageURL == "" {
 		return
 	}
 
-	backgroundImgData, err := DownloadSignImage(result.ImageURL)
+	backgroundImgData, err := DownloadSignImage(ctx, result.ImageURL)
 	if err != nil {
 		log.Printf("Failed to download sign-in image: %v", err)
 		result.ImageData = nil
@@ -126,12 +130,12 @@ func downloadSignCardWithUserInfo(result *SignResult, userInfo *UserInfo, signDa
 	result.ImageData = cardImgData
 }
 
-func downloadImage(result *SignResult) {
+func downloadImage(ctx context.Context, result *SignResult) {
 	if result.ImageURL == "" {
 		return
 	}
 
-	imageData, err := DownloadSignImage(result.ImageURL)
+	imageData, err := DownloadSignImage(ctx, result.ImageURL)
 	if err != nil {
 		log.Printf("Failed to download sign-in image: %v", err)
 		result.ImageData = nil
@@ -141,9 +145,8 @@ func downloadImage(result *SignResult) {
 	result.ImageData = imageData
 }
 
-func DoSign(token string) (*SignResponse, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", "https://2550505.com/sign", nil)
+func DoSign(ctx context.Context, token string) (*SignResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://2550505.com/sign", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -153,43 +156,45 @@ func DoSign(token string) (*SignResponse, error) {
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36")
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("%w: failed to send request: %w", ErrNetwork, err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("%w: failed to read response body: %w", ErrNetwork, err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned non-200 status code: %d, response: %s", resp.StatusCode, string(body))
+		return nil, statusError(resp.StatusCode, body)
 	}
 
 	var signResp SignResponse
 	if err := json.Unmarshal(body, &signResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w, response body: %s", err, string(body))
+		return nil, fmt.Errorf("%w: failed to parse response: %w, response body: %s", ErrBadResponse, err, string(body))
 	}
 
-	if signResp.Code == 104 {
+	switch signResp.Code {
+	case 0:
+		return &signResp, nil
+	case 104:
 		return nil, nil
+	default:
+		return nil, codeError(signResp.Code, signResp.Msg, body)
 	}
-
-	return &signResp, nil
 }
 
-func GetSignDays(token string) (*SignDaysResponse, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", "https://2550505.com/sign/days", nil)
+func GetSignDays(ctx context.Context, token string) (*SignDaysResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://2550505.com/sign/days", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Cookie", "token="+token)
 
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -208,9 +213,8 @@ func GetSignDays(token string) (*SignDaysResponse, error) {
 	return &daysResp, nil
 }
 
-func DownloadSignImage(imageURL string) ([]byte, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", imageURL, nil)
+func DownloadSignImage(ctx context.Context, imageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -219,7 +223,7 @@ func DownloadSignImage(imageURL string) ([]byte, error) {
 	req.Header.Set("Accept", "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8")
 	req.Header.Set("Referer", "https://2550505.com/")
 
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download image: %w", err)
 	}
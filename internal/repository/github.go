@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	ErrSubscriptionExists   = errors.New("subscription already exists")
+)
+
+type GithubSubscription struct {
+	ID         int64     `db:"id"`
+	TargetType string    `db:"target_type"`
+	TargetID   int64     `db:"target_id"`
+	Repo       string    `db:"repo"`
+	Events     string    `db:"events"`   // comma separated, empty means all
+	Branches   string    `db:"branches"` // comma separated, empty means all
+	Labels     string    `db:"labels"`   // comma separated, empty means all
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+type GithubSubscriptionRepository struct {
+	db *sqlx.DB
+}
+
+func NewGithubSubscriptionRepository(db *sqlx.DB) *GithubSubscriptionRepository {
+	return &GithubSubscriptionRepository{db: db}
+}
+
+func (r *GithubSubscriptionRepository) Create(ctx context.Context, sub *GithubSubscription) error {
+	query := `
+		INSERT INTO github_subscriptions (target_type, target_id, repo, events, branches, labels)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, sub.TargetType, sub.TargetID, sub.Repo, sub.Events, sub.Branches, sub.Labels)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrSubscriptionExists
+		}
+		return errors.Join(errors.New("failed to create github subscription"), err)
+	}
+
+	return nil
+}
+
+func (r *GithubSubscriptionRepository) UpdateFilter(ctx context.Context, sub *GithubSubscription) error {
+	query := `
+		UPDATE github_subscriptions
+		SET events = ?, branches = ?, labels = ?
+		WHERE target_type = ? AND target_id = ? AND repo = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query, sub.Events, sub.Branches, sub.Labels, sub.TargetType, sub.TargetID, sub.Repo)
+	if err != nil {
+		return errors.Join(errors.New("failed to update github subscription filter"), err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.Join(errors.New("failed to get affected rows"), err)
+	}
+
+	if rows == 0 {
+		return ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+func (r *GithubSubscriptionRepository) Delete(ctx context.Context, targetType string, targetID int64, repo string) error {
+	query := `
+		DELETE FROM github_subscriptions
+		WHERE target_type = ? AND target_id = ? AND repo = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query, targetType, targetID, repo)
+	if err != nil {
+		return errors.Join(errors.New("failed to delete github subscription"), err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.Join(errors.New("failed to get affected rows"), err)
+	}
+
+	if rows == 0 {
+		return ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+func (r *GithubSubscriptionRepository) GetByTarget(ctx context.Context, targetType string, targetID int64) ([]GithubSubscription, error) {
+	var subscriptions []GithubSubscription
+	query := `
+		SELECT id, target_type, target_id, repo, events, branches, labels, created_at, updated_at
+		FROM github_subscriptions
+		WHERE target_type = ? AND target_id = ?
+		ORDER BY id
+	`
+
+	err := r.db.SelectContext(ctx, &subscriptions, query, targetType, targetID)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get github subscriptions"), err)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *GithubSubscriptionRepository) GetAll(ctx context.Context) ([]GithubSubscription, error) {
+	var subscriptions []GithubSubscription
+	query := `
+		SELECT id, target_type, target_id, repo, events, branches, labels, created_at, updated_at
+		FROM github_subscriptions
+		ORDER BY id
+	`
+
+	err := r.db.SelectContext(ctx, &subscriptions, query)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get all github subscriptions"), err)
+	}
+
+	return subscriptions, nil
+}
+
+type GithubTargetSetting struct {
+	TargetType string    `db:"target_type"`
+	TargetID   int64     `db:"target_id"`
+	Format     string    `db:"format"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+func (r *GithubSubscriptionRepository) UpsertTargetFormat(ctx context.Context, targetType string, targetID int64, format string) error {
+	query := `
+		INSERT INTO github_target_settings (target_type, target_id, format)
+		VALUES (?, ?, ?)
+		ON CONFLICT(target_type, target_id) DO UPDATE SET
+			format = excluded.format,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.ExecContext(ctx, query, targetType, targetID, format)
+	if err != nil {
+		return errors.Join(errors.New("failed to upsert github target setting"), err)
+	}
+
+	return nil
+}
+
+func (r *GithubSubscriptionRepository) GetAllTargetSettings(ctx context.Context) ([]GithubTargetSetting, error) {
+	var settings []GithubTargetSetting
+	query := `
+		SELECT target_type, target_id, format, created_at, updated_at
+		FROM github_target_settings
+	`
+
+	err := r.db.SelectContext(ctx, &settings, query)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get github target settings"), err)
+	}
+
+	return settings, nil
+}
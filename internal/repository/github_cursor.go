@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrCursorNotFound = errors.New("cursor not found")
+
+type GithubCursor struct {
+	Repo        string    `db:"repo"`
+	MonitorType string    `db:"monitor_type"`
+	LastID      string    `db:"last_id"`
+	LastTime    time.Time `db:"last_time"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+type GithubCursorRepository struct {
+	db *sqlx.DB
+}
+
+func NewGithubCursorRepository(db *sqlx.DB) *GithubCursorRepository {
+	return &GithubCursorRepository{db: db}
+}
+
+func (r *GithubCursorRepository) Get(ctx context.Context, repo, monitorType string) (*GithubCursor, error) {
+	var cursor GithubCursor
+	query := `
+		SELECT repo, monitor_type, last_id, last_time, updated_at
+		FROM github_cursors
+		WHERE repo = ? AND monitor_type = ?
+	`
+
+	err := r.db.GetContext(ctx, &cursor, query, repo, monitorType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCursorNotFound
+		}
+		return nil, errors.Join(errors.New("failed to get github cursor"), err)
+	}
+
+	return &cursor, nil
+}
+
+func (r *GithubCursorRepository) Save(ctx context.Context, cursor *GithubCursor) error {
+	query := `
+		INSERT INTO github_cursors (repo, monitor_type, last_id, last_time)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(repo, monitor_type) DO UPDATE SET
+			last_id = excluded.last_id,
+			last_time = excluded.last_time,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.ExecContext(ctx, query, cursor.Repo, cursor.MonitorType, cursor.LastID, cursor.LastTime.UTC())
+	if err != nil {
+		return errors.Join(errors.New("failed to save github cursor"), err)
+	}
+
+	return nil
+}
+
+// MarkDelivered records an event as delivered and reports whether it was new.
+// Events are marked before sending, so a crash mid-send never posts them twice.
+func (r *GithubCursorRepository) MarkDelivered(ctx context.Context, repo, monitorType, eventKey string) (bool, error) {
+	query := `
+		INSERT INTO github_delivered_events (repo, monitor_type, event_key)
+		VALUES (?, ?, ?)
+		ON CONFLICT(repo, monitor_type, event_key) DO NOTHING
+	`
+
+	result, err := r.db.ExecContext(ctx, query, repo, monitorType, eventKey)
+	if err != nil {
+		return false, errors.Join(errors.New("failed to mark github event delivered"), err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Join(errors.New("failed to get affected rows"), err)
+	}
+
+	return rows > 0, nil
+}
+
+func (r *GithubCursorRepository) PruneDelivered(ctx context.Context, before time.Time) error {
+	query := `
+		DELETE FROM github_delivered_events
+		WHERE created_at < ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, before.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return errors.Join(errors.New("failed to prune github delivered events"), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// events are compared as text, so they are always stored in the same layout as CURRENT_TIMESTAMP
+const githubEventTimeLayout = "2006-01-02 15:04:05"
+
+type GithubEvent struct {
+	ID         int64     `db:"id"`
+	Repo       string    `db:"repo"`
+	EventType  string    `db:"event_type"`
+	Action     string    `db:"action"`
+	Branch     string    `db:"branch"`
+	Labels     string    `db:"labels"`
+	Title      string    `db:"title"`
+	Author     string    `db:"author"`
+	URL        string    `db:"url"`
+	OccurredAt time.Time `db:"occurred_at"`
+}
+
+type GithubDigest struct {
+	TargetType string     `db:"target_type"`
+	TargetID   int64      `db:"target_id"`
+	Mode       *string    `db:"mode"`
+	LastSentAt *time.Time `db:"last_sent_at"`
+	UpdatedAt  time.Time  `db:"updated_at"`
+}
+
+type GithubDigestRepository struct {
+	db *sqlx.DB
+}
+
+func NewGithubDigestRepository(db *sqlx.DB) *GithubDigestRepository {
+	return &GithubDigestRepository{db: db}
+}
+
+func (r *GithubDigestRepository) RecordEvent(ctx context.Context, event *GithubEvent) error {
+	query := `
+		INSERT INTO github_events (repo, event_type, action, branch, labels, title, author, url, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		event.Repo,
+		event.EventType,
+		event.Action,
+		event.Branch,
+		event.Labels,
+		event.Title,
+		event.Author,
+		event.URL,
+		event.OccurredAt.UTC().Format(githubEventTimeLayout))
+	if err != nil {
+		return errors.Join(errors.New("failed to record github event"), err)
+	}
+
+	return nil
+}
+
+// ListEventsBetween returns events recorded in [from, to), oldest first
+func (r *GithubDigestRepository) ListEventsBetween(ctx context.Context, from, to time.Time) ([]GithubEvent, error) {
+	var events []GithubEvent
+	query := `
+		SELECT id, repo, event_type, action, branch, labels, title, author, url, occurred_at
+		FROM github_events
+		WHERE occurred_at >= ? AND occurred_at < ?
+		ORDER BY occurred_at ASC, id ASC
+	`
+
+	err := r.db.SelectContext(ctx, &events, query,
+		from.UTC().Format(githubEventTimeLayout),
+		to.UTC().Format(githubEventTimeLayout))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to list github events"), err)
+	}
+
+	return events, nil
+}
+
+func (r *GithubDigestRepository) PruneEvents(ctx context.Context, before time.Time) error {
+	query := `
+		DELETE FROM github_events
+		WHERE occurred_at < ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, before.UTC().Format(githubEventTimeLayout))
+	if err != nil {
+		return errors.Join(errors.New("failed to prune github events"), err)
+	}
+
+	return nil
+}
+
+func (r *GithubDigestRepository) GetAll(ctx context.Context) ([]GithubDigest, error) {
+	var digests []GithubDigest
+	query := `
+		SELECT target_type, target_id, mode, last_sent_at, updated_at
+		FROM github_digests
+	`
+
+	err := r.db.SelectContext(ctx, &digests, query)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get github digests"), err)
+	}
+
+	return digests, nil
+}
+
+// UpsertMode changes the digest mode and restarts the collection period
+func (r *GithubDigestRepository) UpsertMode(ctx context.Context, targetType string, targetID int64, mode string) error {
+	query := `
+		INSERT INTO github_digests (target_type, target_id, mode)
+		VALUES (?, ?, ?)
+		ON CONFLICT(target_type, target_id) DO UPDATE SET
+			mode = excluded.mode,
+			last_sent_at = NULL,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.ExecContext(ctx, query, targetType, targetID, mode)
+	if err != nil {
+		return errors.Join(errors.New("failed to upsert github digest mode"), err)
+	}
+
+	return nil
+}
+
+func (r *GithubDigestRepository) MarkSent(ctx context.Context, targetType string, targetID int64, sentAt time.Time) error {
+	query := `
+		INSERT INTO github_digests (target_type, target_id, last_sent_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(target_type, target_id) DO UPDATE SET
+			last_sent_at = excluded.last_sent_at,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.ExecContext(ctx, query, targetType, targetID, sentAt.UTC())
+	if err != nil {
+		return errors.Join(errors.New("failed to mark github digest sent"), err)
+	}
+
+	return nil
+}
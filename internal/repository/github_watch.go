@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrWatchedRepoNotFound = errors.New("watched repository not found")
+
+type WatchedRepo struct {
+	ID          int64     `db:"id"`
+	Owner       string    `db:"owner"`
+	Name        string    `db:"name"`
+	MonitorType string    `db:"monitor_type"`
+	CreatedBy   int64     `db:"created_by"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// MonitorTypes splits the stored comma separated monitor types
+func (w WatchedRepo) MonitorTypes() []string {
+	if w.MonitorType == "" {
+		return nil
+	}
+	return strings.Split(w.MonitorType, ",")
+}
+
+type GithubWatchRepository struct {
+	db *sqlx.DB
+}
+
+func NewGithubWatchRepository(db *sqlx.DB) *GithubWatchRepository {
+	return &GithubWatchRepository{db: db}
+}
+
+func (r *GithubWatchRepository) Upsert(ctx context.Context, owner, name string, monitorTypes []string, createdBy int64) error {
+	query := `
+		INSERT INTO github_repositories (owner, name, monitor_type, created_by)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(owner, name) DO UPDATE SET
+			monitor_type = excluded.monitor_type,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.ExecContext(ctx, query, owner, name, strings.Join(monitorTypes, ","), createdBy)
+	if err != nil {
+		return errors.Join(errors.New("failed to upsert watched repository"), err)
+	}
+
+	return nil
+}
+
+func (r *GithubWatchRepository) Delete(ctx context.Context, owner, name string) error {
+	query := `
+		DELETE FROM github_repositories
+		WHERE owner = ? AND name = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query, owner, name)
+	if err != nil {
+		return errors.Join(errors.New("failed to delete watched repository"), err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.Join(errors.New("failed to get affected rows"), err)
+	}
+
+	if rows == 0 {
+		return ErrWatchedRepoNotFound
+	}
+
+	return nil
+}
+
+func (r *GithubWatchRepository) GetAll(ctx context.Context) ([]WatchedRepo, error) {
+	var repos []WatchedRepo
+	query := `
+		SELECT id, owner, name, monitor_type, created_by, created_at, updated_at
+		FROM github_repositories
+		ORDER BY id
+	`
+
+	err := r.db.SelectContext(ctx, &repos, query)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get watched repositories"), err)
+	}
+
+	return repos, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	JobStatusRunning = "running"
+	JobStatusSuccess = "success"
+	JobStatusFailed  = "failed"
+	// JobStatusInterrupted marks a run that was still going when the bot stopped
+	JobStatusInterrupted = "interrupted"
+)
+
+// jobTimeLayout stores job times in UTC like CURRENT_TIMESTAMP
+const jobTimeLayout = "2006-01-02 15:04:05"
+
+type JobRun struct {
+	Name           string     `db:"name"`
+	Spec           string     `db:"spec"`
+	LastStartedAt  *time.Time `db:"last_started_at"`
+	LastFinishedAt *time.Time `db:"last_finished_at"`
+	LastStatus     string     `db:"last_status"`
+	LastError      string     `db:"last_error"`
+	NextRunAt      *time.Time `db:"next_run_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+}
+
+type JobRepository struct {
+	db *sqlx.DB
+}
+
+func NewJobRepository(db *sqlx.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+func (r *JobRepository) GetAll(ctx context.Context) ([]JobRun, error) {
+	var runs []JobRun
+	query := `
+		SELECT name, spec, last_started_at, last_finished_at, last_status, last_error, next_run_at, updated_at
+		FROM scheduled_jobs
+		ORDER BY name
+	`
+
+	err := r.db.SelectContext(ctx, &runs, query)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get scheduled jobs"), err)
+	}
+
+	return runs, nil
+}
+
+// Register records a job's schedule, a run left unfinished by the previous process is marked interrupted
+func (r *JobRepository) Register(ctx context.Context, name, spec string, nextRunAt time.Time) error {
+	query := `
+		INSERT INTO scheduled_jobs (name, spec, next_run_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			spec = excluded.spec,
+			next_run_at = excluded.next_run_at,
+			last_status = CASE WHEN last_status = ? THEN ? ELSE last_status END,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.ExecContext(ctx, query, name, spec, formatJobTime(nextRunAt), JobStatusRunning, JobStatusInterrupted)
+	if err != nil {
+		return errors.Join(errors.New("failed to register scheduled job"), err)
+	}
+
+	return nil
+}
+
+func (r *JobRepository) SetNextRun(ctx context.Context, name string, nextRunAt time.Time) error {
+	query := `
+		UPDATE scheduled_jobs
+		SET next_run_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE name = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, formatJobTime(nextRunAt), name)
+	if err != nil {
+		return errors.Join(errors.New("failed to update scheduled job"), err)
+	}
+
+	return nil
+}
+
+func (r *JobRepository) MarkStarted(ctx context.Context, name string, startedAt time.Time) error {
+	query := `
+		UPDATE scheduled_jobs
+		SET last_started_at = ?, last_status = ?, last_error = '', updated_at = CURRENT_TIMESTAMP
+		WHERE name = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, formatJobTime(startedAt), JobStatusRunning, name)
+	if err != nil {
+		return errors.Join(errors.New("failed to mark scheduled job started"), err)
+	}
+
+	return nil
+}
+
+func (r *JobRepository) MarkFinished(ctx context.Context, name string, finishedAt time.Time, runErr error) error {
+	status, message := JobStatusSuccess, ""
+	if runErr != nil {
+		status, message = JobStatusFailed, runErr.Error()
+	}
+
+	query := `
+		UPDATE scheduled_jobs
+		SET last_finished_at = ?, last_status = ?, last_error = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE name = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, formatJobTime(finishedAt), status, message, name)
+	if err != nil {
+		return errors.Join(errors.New("failed to mark scheduled job finished"), err)
+	}
+
+	return nil
+}
+
+// formatJobTime stores the zero time, used for schedules that never fire again, as NULL
+func formatJobTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(jobTimeLayout)
+}
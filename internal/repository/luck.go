@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type LuckRepository struct {
+	db *sqlx.DB
+}
+
+func NewLuckRepository(db *sqlx.DB) *LuckRepository {
+	return &LuckRepository{db: db}
+}
+
+// Get returns the luck value a user drew on the given day, and whether one was drawn
+func (r *LuckRepository) Get(ctx context.Context, userID string, day string) (int, bool, error) {
+	var value int
+	query := `SELECT value FROM user_luck WHERE user_id = ? AND day = ?`
+
+	err := r.db.GetContext(ctx, &value, query, userID, day)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, errors.Join(errors.New("failed to get user luck"), err)
+	}
+
+	return value, true, nil
+}
+
+func (r *LuckRepository) Record(ctx context.Context, userID string, day string, value int) error {
+	query := `INSERT INTO user_luck (user_id, day, value) VALUES (?, ?, ?)`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, day, value); err != nil {
+		return errors.Join(errors.New("failed to record user luck"), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrPluginSettingNotFound = errors.New("plugin setting not found")
+
+// GlobalPluginGroup is the group ID under which global plugin defaults are stored
+const GlobalPluginGroup int64 = 0
+
+type PluginSetting struct {
+	GroupID   int64     `db:"group_id"`
+	Plugin    string    `db:"plugin"`
+	Enabled   bool      `db:"enabled"`
+	UpdatedBy int64     `db:"updated_by"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type PluginRepository struct {
+	db *sqlx.DB
+}
+
+func NewPluginRepository(db *sqlx.DB) *PluginRepository {
+	return &PluginRepository{db: db}
+}
+
+func (r *PluginRepository) GetAll(ctx context.Context) ([]PluginSetting, error) {
+	var settings []PluginSetting
+	query := `
+		SELECT group_id, plugin, enabled, updated_by, updated_at
+		FROM plugin_settings
+	`
+
+	err := r.db.SelectContext(ctx, &settings, query)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get plugin settings"), err)
+	}
+
+	return settings, nil
+}
+
+func (r *PluginRepository) Set(ctx context.Context, groupID int64, plugin string, enabled bool, updatedBy int64) error {
+	query := `
+		INSERT INTO plugin_settings (group_id, plugin, enabled, updated_by)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(group_id, plugin) DO UPDATE SET
+			enabled = excluded.enabled,
+			updated_by = excluded.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.ExecContext(ctx, query, groupID, plugin, enabled, updatedBy)
+	if err != nil {
+		return errors.Join(errors.New("failed to set plugin setting"), err)
+	}
+
+	return nil
+}
+
+func (r *PluginRepository) Delete(ctx context.Context, groupID int64, plugin string) error {
+	query := `
+		DELETE FROM plugin_settings
+		WHERE group_id = ? AND plugin = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query, groupID, plugin)
+	if err != nil {
+		return errors.Join(errors.New("failed to delete plugin setting"), err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.Join(errors.New("failed to get affected rows"), err)
+	}
+
+	if rows == 0 {
+		return ErrPluginSettingNotFound
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"sync/atomic"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -14,32 +15,91 @@ const (
 	SignStatusPending SignStatus = iota
 	SignStatusSuccess
 	SignStatusFailed
+	// SignStatusAbandoned is a failure that retrying won't fix, such as an expired token
+	SignStatusAbandoned
+)
+
+// SignFailureReason classifies why the last attempt of a record failed
+type SignFailureReason string
+
+const (
+	SignFailureNone    SignFailureReason = ""
+	SignFailureNetwork SignFailureReason = "network"
+	SignFailureServer  SignFailureReason = "server"
+	SignFailureAuth    SignFailureReason = "auth"
+	SignFailureDecrypt SignFailureReason = "decrypt"
+	// SignFailureRejected is an error code or a 4xx status from MGClub other than an invalid token
+	SignFailureRejected SignFailureReason = "rejected"
+	// SignFailureResponse is a response that could not be decoded
+	SignFailureResponse SignFailureReason = "response"
+	SignFailureUnknown  SignFailureReason = "unknown"
 )
 
 type SignRecord struct {
-	ID          int64      `db:"id"`
-	UserID      string     `db:"user_id"`
-	SignDate    time.Time  `db:"sign_date"`
-	Status      SignStatus `db:"status"`
-	RetryCount  int        `db:"retry_count"`
-	LastRetryAt *time.Time `db:"last_retry_at"`
-	CreatedAt   time.Time  `db:"created_at"`
-	UpdatedAt   time.Time  `db:"updated_at"`
+	ID            int64             `db:"id"`
+	UserID        string            `db:"user_id"`
+	SignDate      time.Time         `db:"sign_date"`
+	Status        SignStatus        `db:"status"`
+	RetryCount    int               `db:"retry_count"`
+	LastRetryAt   *time.Time        `db:"last_retry_at"`
+	ScheduledAt   *time.Time        `db:"scheduled_at"`
+	NextRetryAt   *time.Time        `db:"next_retry_at"`
+	FailureReason SignFailureReason `db:"failure_reason"`
+	Exp           int               `db:"exp"`
+	Manual        bool              `db:"manual"`
+	CreatedAt     time.Time         `db:"created_at"`
+	UpdatedAt     time.Time         `db:"updated_at"`
+}
+
+const (
+	// scheduled and retry times are stored as wall clock times in the sign-in time zone
+	signTimeLayout = "2006-01-02 15:04:05"
+	signDateLayout = "2006-01-02"
+)
+
+// SignSchedule is the time a user should be signed in today, nil for as soon as possible
+type SignSchedule struct {
+	UserID      string
+	ScheduledAt *time.Time
 }
 
 type SignRepository struct {
-	db *sqlx.DB
+	db  *sqlx.DB
+	loc atomic.Pointer[time.Location]
 }
 
 func NewSignRepository(db *sqlx.DB) *SignRepository {
-	return &SignRepository{db: db}
+	r := &SignRepository{db: db}
+	r.SetLocation(time.Local)
+	return r
+}
+
+// SetLocation changes the time zone that decides which day a sign-in belongs to
+func (r *SignRepository) SetLocation(loc *time.Location) {
+	r.loc.Store(loc)
 }
 
-func (r *SignRepository) InitDailyRecords(ctx context.Context, userIDs []string) error {
-	today := time.Now().Format("2006-01-02")
+func (r *SignRepository) now() time.Time {
+	return time.Now().In(r.loc.Load())
+}
+
+func (r *SignRepository) today() string {
+	return r.now().Format(signDateLayout)
+}
+
+func (r *SignRepository) formatSignTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.In(r.loc.Load()).Format(signTimeLayout)
+}
+
+// InitDailyRecords creates today's records, a record that already exists keeps its scheduled time
+func (r *SignRepository) InitDailyRecords(ctx context.Context, schedules []SignSchedule) error {
+	today := r.today()
 	query := `
-		INSERT INTO sign_records (user_id, sign_date)
-		VALUES (?, ?)
+		INSERT INTO sign_records (user_id, sign_date, scheduled_at)
+		VALUES (?, ?, ?)
 		ON CONFLICT(user_id, sign_date) DO NOTHING
 	`
 
@@ -55,8 +115,8 @@ func (r *SignRepository) InitDailyRecords(ctx context.Context, userIDs []string)
 	}
 	defer stmt.Close()
 
-	for _, userID := range userIDs {
-		if _, err := stmt.ExecContext(ctx, userID, today); err != nil {
+	for _, schedule := range schedules {
+		if _, err := stmt.ExecContext(ctx, schedule.UserID, today, r.formatSignTime(schedule.ScheduledAt)); err != nil {
 			return err
 		}
 	}
@@ -64,18 +124,22 @@ func (r *SignRepository) InitDailyRecords(ctx context.Context, userIDs []string)
 	return tx.Commit()
 }
 
+// GetPendingRecords returns today's unfinished records whose scheduled time has come
 func (r *SignRepository) GetPendingRecords(ctx context.Context, maxRetries int) ([]SignRecord, error) {
 	query := `
-		SELECT id, user_id, sign_date, status, retry_count, last_retry_at, created_at, updated_at
+		SELECT id, user_id, sign_date, status, retry_count, last_retry_at, scheduled_at, next_retry_at, failure_reason, exp, manual, created_at, updated_at
 		FROM sign_records
-		WHERE sign_date = DATE('now', 'localtime')
-		AND status != ?
+		WHERE sign_date = ?
+		AND status NOT IN (?, ?)
 		AND retry_count < ?
-		AND (last_retry_at IS NULL OR last_retry_at < datetime('now', '-5 minutes'))
+		AND (next_retry_at IS NULL OR next_retry_at <= ?)
+		AND (scheduled_at IS NULL OR scheduled_at <= ?)
 	`
 
+	now := r.now()
+	nowText := now.Format(signTimeLayout)
 	var records []SignRecord
-	err := r.db.SelectContext(ctx, &records, query, SignStatusSuccess, maxRetries)
+	err := r.db.SelectContext(ctx, &records, query, now.Format(signDateLayout), SignStatusSuccess, SignStatusAbandoned, maxRetries, nowText, nowText)
 	if err != nil {
 		return nil, err
 	}
@@ -83,16 +147,165 @@ func (r *SignRepository) GetPendingRecords(ctx context.Context, maxRetries int)
 	return records, nil
 }
 
-func (r *SignRepository) UpdateStatus(ctx context.Context, id int64, status SignStatus) error {
+func (r *SignRepository) GetTodayRecord(ctx context.Context, userID string) (*SignRecord, error) {
+	query := `
+		SELECT id, user_id, sign_date, status, retry_count, last_retry_at, scheduled_at, next_retry_at, failure_reason, exp, manual, created_at, updated_at
+		FROM sign_records
+		WHERE sign_date = ?
+		AND user_id = ?
+	`
+
+	var record SignRecord
+	err := r.db.GetContext(ctx, &record, query, r.today(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// Reschedule moves today's record of a user to a new time, unless it has already succeeded
+func (r *SignRepository) Reschedule(ctx context.Context, userID string, scheduledAt *time.Time) error {
+	query := `
+		UPDATE sign_records
+		SET scheduled_at = ?
+		WHERE sign_date = ?
+		AND user_id = ?
+		AND status != ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, r.formatSignTime(scheduledAt), r.today(), userID, SignStatusSuccess)
+	return err
+}
+
+// MarkSuccess records a successful attempt and the EXP it gained, 0 when the user had already signed in
+func (r *SignRepository) MarkSuccess(ctx context.Context, id int64, exp int) error {
+	query := `
+		UPDATE sign_records
+		SET status = ?,
+			retry_count = retry_count + 1,
+			last_retry_at = CURRENT_TIMESTAMP,
+			next_retry_at = NULL,
+			failure_reason = '',
+			exp = ?
+		WHERE id = ?
+	`
+
+	return r.exec(ctx, query, SignStatusSuccess, exp, id)
+}
+
+// RecordManual records a sign-in done with /255sign as today's success, so the automatic one is skipped.
+// A record that has already succeeded is kept as it is.
+func (r *SignRepository) RecordManual(ctx context.Context, userID string, exp int) error {
+	today := r.today()
+	query := `
+		INSERT INTO sign_records (user_id, sign_date, status, retry_count, last_retry_at, exp, manual)
+		VALUES (?, ?, ?, 1, CURRENT_TIMESTAMP, ?, 1)
+		ON CONFLICT(user_id, sign_date) DO UPDATE SET
+			status = excluded.status,
+			retry_count = sign_records.retry_count + 1,
+			last_retry_at = excluded.last_retry_at,
+			next_retry_at = NULL,
+			failure_reason = '',
+			exp = excluded.exp,
+			manual = 1
+		WHERE sign_records.status != excluded.status
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, today, SignStatusSuccess, exp)
+	return err
+}
+
+// MarkFailed records a failed attempt, nextRetryAt is ignored for SignStatusAbandoned
+func (r *SignRepository) MarkFailed(ctx context.Context, id int64, status SignStatus, reason SignFailureReason, nextRetryAt time.Time) error {
+	var next *time.Time
+	if status != SignStatusAbandoned {
+		next = &nextRetryAt
+	}
+
 	query := `
 		UPDATE sign_records
 		SET status = ?,
 			retry_count = retry_count + 1,
-			last_retry_at = CURRENT_TIMESTAMP
+			last_retry_at = CURRENT_TIMESTAMP,
+			next_retry_at = ?,
+			failure_reason = ?
 		WHERE id = ?
 	`
 
-	result, err := r.db.ExecContext(ctx, query, status, id)
+	return r.exec(ctx, query, status, r.formatSignTime(next), reason, id)
+}
+
+// Resume makes today's abandoned record of a user pending again, e.g. after the token was bound again
+func (r *SignRepository) Resume(ctx context.Context, userID string) error {
+	query := `
+		UPDATE sign_records
+		SET status = ?,
+			retry_count = 0,
+			next_retry_at = NULL,
+			failure_reason = ''
+		WHERE sign_date = ?
+		AND user_id = ?
+		AND status = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, SignStatusPending, r.today(), userID, SignStatusAbandoned)
+	return err
+}
+
+// SignDay is the outcome of one day's sign-in
+type SignDay struct {
+	UserID   string     `db:"user_id"`
+	SignDate time.Time  `db:"sign_date"`
+	Status   SignStatus `db:"status"`
+	Manual   bool       `db:"manual"`
+	Exp      int        `db:"exp"`
+}
+
+// GetHistory returns every day a user has a record for, oldest first
+func (r *SignRepository) GetHistory(ctx context.Context, userID string) ([]SignDay, error) {
+	query := `
+		SELECT user_id, sign_date, status, manual, exp
+		FROM sign_records
+		WHERE user_id = ?
+		ORDER BY sign_date
+	`
+
+	var days []SignDay
+	if err := r.db.SelectContext(ctx, &days, query, userID); err != nil {
+		return nil, err
+	}
+
+	return days, nil
+}
+
+// GetSuccessDays returns the successful days of the given users, ordered by user and then date
+func (r *SignRepository) GetSuccessDays(ctx context.Context, userIDs []string) ([]SignDay, error) {
+	var days []SignDay
+	if len(userIDs) == 0 {
+		return days, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT user_id, sign_date, status, manual, exp
+		FROM sign_records
+		WHERE status = ?
+		AND user_id IN (?)
+		ORDER BY user_id, sign_date
+	`, SignStatusSuccess, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.SelectContext(ctx, &days, r.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	return days, nil
+}
+
+func (r *SignRepository) exec(ctx context.Context, query string, args ...any) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"PakuchiBot/internal/storage"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// openTestDB opens a migrated database in a temporary directory, closed when the test ends
+func openTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	if err := storage.InitDB(filepath.Join(t.TempDir(), "bot.db")); err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { storage.CloseDB() })
+	return storage.GetDB()
+}
+
+func TestGetSuccessDaysFiltersUsers(t *testing.T) {
+	db := openTestDB(t)
+	signRepo := NewSignRepository(db)
+
+	for _, row := range []struct {
+		userID string
+		date   string
+		status SignStatus
+	}{
+		{"1001", "2026-10-15", SignStatusSuccess},
+		{"1001", "2026-10-16", SignStatusSuccess},
+		{"1001", "2026-10-17", SignStatusFailed},
+		{"1002", "2026-10-16", SignStatusSuccess},
+		{"2001", "2026-10-16", SignStatusSuccess},
+	} {
+		if _, err := db.Exec(`INSERT INTO sign_records (user_id, sign_date, status) VALUES (?, ?, ?)`, row.userID, row.date, row.status); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	days, err := signRepo.GetSuccessDays(context.Background(), []string{"1001", "1002", "3001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, day := range days {
+		got = append(got, day.UserID+"@"+day.SignDate.Format(signDateLayout))
+	}
+	want := []string{"1001@2026-10-15", "1001@2026-10-16", "1002@2026-10-16"}
+	if len(got) != len(want) {
+		t.Fatalf("GetSuccessDays() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GetSuccessDays()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	if days, err := signRepo.GetSuccessDays(context.Background(), nil); err != nil || len(days) != 0 {
+		t.Errorf("GetSuccessDays(nil) = %v, %v, want no days", days, err)
+	}
+}
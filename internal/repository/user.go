@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -15,11 +16,15 @@ var (
 )
 
 type User struct {
-	ID        int64     `db:"id"`
-	UserID    string    `db:"user_id"`
-	Token     string    `db:"token"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID     int64  `db:"id"`
+	UserID string `db:"user_id"`
+	Token  string `db:"token"`
+	// SignWindow is the daily auto sign-in window as HH:MM-HH:MM, empty for the default one
+	SignWindow string `db:"sign_window"`
+	// TokenInvalidAt is set when the token was rejected, auto sign-in pauses until it is bound again
+	TokenInvalidAt *time.Time `db:"token_invalid_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
 }
 
 type UserRepository struct {
@@ -47,7 +52,7 @@ func (r *UserRepository) Create(ctx context.Context, userID, token string) error
 func (r *UserRepository) Update(ctx context.Context, userID, token string) error {
 	query := `
 		UPDATE mgclub_users
-		SET token = ?
+		SET token = ?, token_invalid_at = NULL
 		WHERE user_id = ?
 	`
 
@@ -71,7 +76,7 @@ func (r *UserRepository) Update(ctx context.Context, userID, token string) error
 func (r *UserRepository) GetByUserID(ctx context.Context, userID string) (*User, error) {
 	var user User
 	query := `
-		SELECT id, user_id, token, created_at, updated_at
+		SELECT id, user_id, token, sign_window, token_invalid_at, created_at, updated_at
 		FROM mgclub_users
 		WHERE user_id = ?
 	`
@@ -90,7 +95,7 @@ func (r *UserRepository) GetByUserID(ctx context.Context, userID string) (*User,
 func (r *UserRepository) GetAllUsers(ctx context.Context) ([]User, error) {
 	var users []User
 	query := `
-		SELECT id, user_id, token, created_at, updated_at
+		SELECT id, user_id, token, sign_window, token_invalid_at, created_at, updated_at
 		FROM mgclub_users
 	`
 
@@ -102,6 +107,91 @@ func (r *UserRepository) GetAllUsers(ctx context.Context) ([]User, error) {
 	return users, nil
 }
 
+// RotateTokens re-encrypts every stored token in a single transaction, nothing is written if any row fails
+func (r *UserRepository) RotateTokens(ctx context.Context, reencrypt func(token string) (string, error), needsRotation func(token string) bool) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, errors.Join(errors.New("failed to begin transaction"), err)
+	}
+	defer tx.Rollback()
+
+	var users []User
+	query := `
+		SELECT id, user_id, token, sign_window, token_invalid_at, created_at, updated_at
+		FROM mgclub_users
+	`
+	if err := tx.SelectContext(ctx, &users, query); err != nil {
+		return 0, errors.Join(errors.New("failed to get all users"), err)
+	}
+
+	rotated := 0
+	for _, user := range users {
+		if !needsRotation(user.Token) {
+			continue
+		}
+
+		token, err := reencrypt(user.Token)
+		if err != nil {
+			return 0, errors.Join(fmt.Errorf("failed to re-encrypt token of user %s", user.UserID), err)
+		}
+
+		query := `
+			UPDATE mgclub_users
+			SET token = ?
+			WHERE id = ?
+		`
+		if _, err := tx.ExecContext(ctx, query, token, user.ID); err != nil {
+			return 0, errors.Join(errors.New("failed to update user token"), err)
+		}
+		rotated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Join(errors.New("failed to commit transaction"), err)
+	}
+
+	return rotated, nil
+}
+
+func (r *UserRepository) SetSignWindow(ctx context.Context, userID, window string) error {
+	query := `
+		UPDATE mgclub_users
+		SET sign_window = ?
+		WHERE user_id = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query, window, userID)
+	if err != nil {
+		return errors.Join(errors.New("failed to update sign window"), err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.Join(errors.New("failed to get affected rows"), err)
+	}
+
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetTokenInvalid pauses auto sign-in for a user until the token is bound again, or lifts the pause
+func (r *UserRepository) SetTokenInvalid(ctx context.Context, userID string, invalid bool) error {
+	query := `
+		UPDATE mgclub_users
+		SET token_invalid_at = CASE WHEN ? THEN CURRENT_TIMESTAMP END
+		WHERE user_id = ?
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, invalid, userID); err != nil {
+		return errors.Join(errors.New("failed to update token state"), err)
+	}
+
+	return nil
+}
+
 func (r *UserRepository) Delete(ctx context.Context, userID string) error {
 	query := `
 		DELETE FROM mgclub_users
@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"PakuchiBot/internal/repository"
+	"PakuchiBot/internal/storage"
+)
+
+// JobDBCleanup is the scheduler job that prunes the bot's own bookkeeping and compacts the database
+const JobDBCleanup = "db_cleanup"
+
+// cleanupRetention is how long GitHub delivery records and digested events are kept
+const cleanupRetention = 30 * 24 * time.Hour
+
+// CleanupTask only touches rows the bot can rebuild, user data such as luck history is never pruned
+type CleanupTask struct {
+	cursorRepo *repository.GithubCursorRepository
+	digestRepo *repository.GithubDigestRepository
+}
+
+func NewCleanupTask(
+	cursorRepo *repository.GithubCursorRepository,
+	digestRepo *repository.GithubDigestRepository,
+) *CleanupTask {
+	return &CleanupTask{
+		cursorRepo: cursorRepo,
+		digestRepo: digestRepo,
+	}
+}
+
+// Job wraps Run for the scheduler
+func (t *CleanupTask) Job() Job {
+	return Job{Name: JobDBCleanup, Description: "清理过期数据", Run: t.Run}
+}
+
+// Run prunes delivered and digested GitHub events and reports all failures together
+func (t *CleanupTask) Run(ctx context.Context) error {
+	before := time.Now().Add(-cleanupRetention)
+
+	return errors.Join(
+		t.cursorRepo.PruneDelivered(ctx, before),
+		t.digestRepo.PruneEvents(ctx, before),
+		storage.Optimize(ctx),
+	)
+}
@@ -3,68 +3,350 @@ package scheduler
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"PakuchiBot/internal/repository"
-	"PakuchiBot/internal/utils"
-
-	zero "github.com/wdvxdr1123/ZeroBot"
 )
 
 var (
-	ErrSchedulerRunning = errors.New("scheduler is already running")
-	ErrSchedulerStopped = errors.New("scheduler is not running")
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+	ErrJobExists   = errors.New("job already registered")
 )
 
+// Job is a named task run on a cron schedule
+type Job struct {
+	Name        string
+	Description string
+	Run         func(ctx context.Context) error
+}
+
+// JobStatus describes a registered job together with its last recorded run
+type JobStatus struct {
+	Job
+	Spec    string
+	Running bool
+	NextRun time.Time // zero when the schedule never fires again
+	LastRun *repository.JobRun
+}
+
+type job struct {
+	Job
+	spec     string
+	schedule Schedule
+	next     time.Time
+	running  atomic.Bool
+}
+
+// Scheduler runs registered jobs when their schedules fire, a job that is still running when
+// it fires again is skipped instead of being started twice
 type Scheduler struct {
-	signTask    *SignTask
-	checkTicker *time.Ticker
-	ctx         context.Context
-	cancel      context.CancelFunc
-}
-
-func NewScheduler(
-	userRepo *repository.UserRepository,
-	signRepo *repository.SignRepository,
-	notifyRepo *repository.NotifyRepository,
-	crypto *utils.TokenCrypto,
-	bot *zero.Ctx,
-	checkInterval time.Duration,
-	maxRetries int,
-) *Scheduler {
+	repo *repository.JobRepository
+
+	mu   sync.Mutex
+	loc  *time.Location
+	jobs []*job
+
+	wake   chan struct{}
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewScheduler(repo *repository.JobRepository, loc *time.Location) *Scheduler {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
-		signTask: NewSignTask(
-			userRepo,
-			signRepo,
-			notifyRepo,
-			crypto,
-			bot,
-			maxRetries,
-		),
-		checkTicker: time.NewTicker(checkInterval),
-		ctx:         ctx,
-		cancel:      cancel,
+		repo:   repo,
+		loc:    loc,
+		wake:   make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Register adds a job with its cron expression, see ParseSpec for the accepted syntax
+func (s *Scheduler) Register(j Job, spec string) error {
+	s.mu.Lock()
+	if s.find(j.Name) != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobExists, j.Name)
+	}
+
+	schedule, err := ParseSpec(spec, s.loc)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	registered := &job{Job: j, spec: spec, schedule: schedule, next: schedule.Next(time.Now())}
+	s.jobs = append(s.jobs, registered)
+	s.mu.Unlock()
+
+	s.persistSchedule(registered.Name, spec, registered.next)
+	s.notify()
+	return nil
+}
+
+// Reschedule replaces a job's cron expression, the next run is computed from now
+func (s *Scheduler) Reschedule(name, spec string) error {
+	s.mu.Lock()
+	j := s.find(name)
+	if j == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if j.spec == spec {
+		s.mu.Unlock()
+		return nil
+	}
+
+	schedule, err := ParseSpec(spec, s.loc)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	j.spec, j.schedule, j.next = spec, schedule, schedule.Next(time.Now())
+	next := j.next
+	s.mu.Unlock()
+
+	log.Printf("job %s rescheduled to %q", name, spec)
+	s.persistSchedule(name, spec, next)
+	s.notify()
+	return nil
+}
+
+// SetLocation changes the time zone of expressions without a CRON_TZ prefix
+func (s *Scheduler) SetLocation(loc *time.Location) {
+	s.mu.Lock()
+	if s.loc.String() == loc.String() {
+		s.mu.Unlock()
+		return
 	}
+	s.loc = loc
+
+	now := time.Now()
+	type update struct {
+		name, spec string
+		next       time.Time
+	}
+	var updates []update
+	for _, j := range s.jobs {
+		// the expression was valid before and the zone doesn't affect parsing
+		schedule, err := ParseSpec(j.spec, loc)
+		if err != nil {
+			continue
+		}
+		j.schedule, j.next = schedule, schedule.Next(now)
+		updates = append(updates, update{j.Name, j.spec, j.next})
+	}
+	s.mu.Unlock()
+
+	log.Printf("scheduler time zone changed to %s", loc)
+	for _, u := range updates {
+		s.persistSchedule(u.name, u.spec, u.next)
+	}
+	s.notify()
 }
 
 func (s *Scheduler) Start() {
 	log.Printf("scheduler started")
-	go func() {
-		for {
-			select {
-			case <-s.ctx.Done():
-				log.Printf("scheduler stopped")
-				return
-			case <-s.checkTicker.C:
-				s.signTask.Run(s.ctx)
-			}
-		}
-	}()
+	go s.loop()
 }
 
+// Stop cancels running jobs and waits for them to return
 func (s *Scheduler) Stop() {
 	s.cancel()
-	s.checkTicker.Stop()
+	s.wg.Wait()
+}
+
+// Has reports whether a job is registered
+func (s *Scheduler) Has(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.find(name) != nil
+}
+
+// RunNow runs a job right away and returns its result, unless it is already running
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.Lock()
+	j := s.find(name)
+	s.mu.Unlock()
+	if j == nil {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	if !j.running.CompareAndSwap(false, true) {
+		return fmt.Errorf("%w: %s", ErrJobRunning, name)
+	}
+	s.wg.Add(1)
+	defer s.wg.Done()
+	defer j.running.Store(false)
+
+	return s.execute(j)
+}
+
+// Statuses lists the registered jobs in registration order with their last recorded run
+func (s *Scheduler) Statuses(ctx context.Context) ([]JobStatus, error) {
+	runs, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lastRuns := make(map[string]*repository.JobRun, len(runs))
+	for i := range runs {
+		lastRuns[runs[i].Name] = &runs[i]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, len(s.jobs))
+	for i, j := range s.jobs {
+		statuses[i] = JobStatus{
+			Job:     j.Job,
+			Spec:    j.spec,
+			Running: j.running.Load(),
+			NextRun: j.next,
+			LastRun: lastRuns[j.Name],
+		}
+	}
+	return statuses, nil
+}
+
+// Location returns the time zone used for expressions without a CRON_TZ prefix
+func (s *Scheduler) Location() *time.Location {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loc
+}
+
+func (s *Scheduler) loop() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		timer.Reset(s.untilNextRun(time.Now()))
+
+		select {
+		case <-s.ctx.Done():
+			log.Printf("scheduler stopped")
+			return
+		case <-s.wake:
+		case now := <-timer.C:
+			s.runDue(now)
+		}
+	}
+}
+
+// untilNextRun returns how long to sleep, an hour when nothing is scheduled so new jobs are noticed anyway
+func (s *Scheduler) untilNextRun(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := time.Hour
+	for _, j := range s.jobs {
+		if j.next.IsZero() {
+			continue
+		}
+		if d := j.next.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	return max(wait, 0)
+}
+
+func (s *Scheduler) runDue(now time.Time) {
+	s.mu.Lock()
+	var due []*job
+	for _, j := range s.jobs {
+		if j.next.IsZero() || j.next.After(now) {
+			continue
+		}
+		j.next = j.schedule.Next(now)
+		due = append(due, j)
+	}
+	s.mu.Unlock()
+
+	for _, j := range due {
+		if !j.running.CompareAndSwap(false, true) {
+			log.Printf("job %s is still running, skipping this run", j.Name)
+			s.mu.Lock()
+			next := j.next
+			s.mu.Unlock()
+			s.record(func(ctx context.Context) error { return s.repo.SetNextRun(ctx, j.Name, next) })
+			continue
+		}
+
+		s.wg.Add(1)
+		go func(j *job) {
+			defer s.wg.Done()
+			defer j.running.Store(false)
+			s.execute(j)
+		}(j)
+	}
+}
+
+// execute runs a job and records the outcome, the caller must hold the job's running flag
+func (s *Scheduler) execute(j *job) (err error) {
+	started := time.Now()
+	s.record(func(ctx context.Context) error { return s.repo.MarkStarted(ctx, j.Name, started) })
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+			log.Printf("job %s panicked: %v\n%s", j.Name, r, debug.Stack())
+		}
+
+		s.mu.Lock()
+		next := j.next
+		s.mu.Unlock()
+
+		s.record(func(ctx context.Context) error { return s.repo.MarkFinished(ctx, j.Name, time.Now(), err) })
+		s.record(func(ctx context.Context) error { return s.repo.SetNextRun(ctx, j.Name, next) })
+
+		if err != nil {
+			log.Printf("job %s failed after %s: %v", j.Name, time.Since(started).Round(time.Millisecond), err)
+		} else {
+			log.Printf("job %s finished in %s", j.Name, time.Since(started).Round(time.Millisecond))
+		}
+	}()
+
+	return j.Run(s.ctx)
+}
+
+func (s *Scheduler) persistSchedule(name, spec string, next time.Time) {
+	s.record(func(ctx context.Context) error { return s.repo.Register(ctx, name, spec, next) })
+}
+
+// record writes job bookkeeping, a failure is logged but never stops the job itself
+func (s *Scheduler) record(write func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := write(ctx); err != nil {
+		log.Printf("failed to record job state: %v", err)
+	}
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// find must be called with mu held
+func (s *Scheduler) find(name string) *job {
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return j
+		}
+	}
+	return nil
 }
@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"PakuchiBot/internal/repository"
+	"PakuchiBot/internal/storage"
+)
+
+func newTestScheduler(t *testing.T, loc *time.Location) *Scheduler {
+	t.Helper()
+	if err := storage.InitDB(filepath.Join(t.TempDir(), "bot.db")); err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { storage.CloseDB() })
+
+	s := NewScheduler(repository.NewJobRepository(storage.GetDB()), loc)
+	t.Cleanup(s.Stop)
+	return s
+}
+
+func status(t *testing.T, s *Scheduler, name string) JobStatus {
+	t.Helper()
+	statuses, err := s.Statuses(context.Background())
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	for _, st := range statuses {
+		if st.Name == name {
+			return st
+		}
+	}
+	t.Fatalf("job %s is not registered", name)
+	return JobStatus{}
+}
+
+func noop(context.Context) error { return nil }
+
+func TestRegisterAndReschedule(t *testing.T) {
+	s := newTestScheduler(t, time.UTC)
+
+	if err := s.Register(Job{Name: "report", Run: noop}, "0 0 * * *"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := s.Register(Job{Name: "report", Run: noop}, "@hourly"); !errors.Is(err, ErrJobExists) {
+		t.Errorf("Register() of a duplicate error = %v, want ErrJobExists", err)
+	}
+	if err := s.Register(Job{Name: "broken", Run: noop}, "0 0 * *"); !errors.Is(err, ErrInvalidSpec) {
+		t.Errorf("Register() of an invalid spec error = %v, want ErrInvalidSpec", err)
+	}
+	if s.Has("broken") {
+		t.Error("a job with an invalid spec was registered")
+	}
+
+	st := status(t, s, "report")
+	if st.NextRun.Hour() != 0 || st.NextRun.Minute() != 0 || !st.NextRun.After(time.Now()) {
+		t.Errorf("next run = %v, want the coming midnight", st.NextRun)
+	}
+	if st.LastRun == nil || st.LastRun.Spec != "0 0 * * *" {
+		t.Errorf("stored run = %+v, want spec %q", st.LastRun, "0 0 * * *")
+	}
+
+	if err := s.Reschedule("missing", "@hourly"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Reschedule() of an unknown job error = %v, want ErrJobNotFound", err)
+	}
+	if err := s.Reschedule("report", "61 * * * *"); !errors.Is(err, ErrInvalidSpec) {
+		t.Errorf("Reschedule() to an invalid spec error = %v, want ErrInvalidSpec", err)
+	}
+	if err := s.Reschedule("report", "30 * * * *"); err != nil {
+		t.Fatalf("Reschedule() error = %v", err)
+	}
+
+	st = status(t, s, "report")
+	if st.Spec != "30 * * * *" || st.NextRun.Minute() != 30 || st.NextRun.Sub(time.Now()) > time.Hour {
+		t.Errorf("after reschedule spec = %q, next run = %v", st.Spec, st.NextRun)
+	}
+	if st.LastRun == nil || st.LastRun.Spec != "30 * * * *" {
+		t.Errorf("stored run = %+v, want spec %q", st.LastRun, "30 * * * *")
+	}
+}
+
+func TestSetLocationKeepsCronTZ(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	s := newTestScheduler(t, time.UTC)
+
+	if err := s.Register(Job{Name: "local", Run: noop}, "0 9 * * *"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := s.Register(Job{Name: "pinned", Run: noop}, "CRON_TZ=UTC 0 9 * * *"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	s.SetLocation(tokyo)
+
+	if s.Location() != tokyo {
+		t.Errorf("Location() = %v, want %v", s.Location(), tokyo)
+	}
+	if next := status(t, s, "local").NextRun.In(time.UTC); next.Hour() != 0 {
+		t.Errorf("local job next run = %v, want 09:00 in Tokyo", next)
+	}
+	if next := status(t, s, "pinned").NextRun.In(time.UTC); next.Hour() != 9 {
+		t.Errorf("pinned job next run = %v, want 09:00 UTC", next)
+	}
+}
+
+func TestRunningJobIsSkipped(t *testing.T) {
+	s := newTestScheduler(t, time.UTC)
+
+	var runs atomic.Int32
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	blocking := Job{Name: "slow", Run: func(ctx context.Context) error {
+		runs.Add(1)
+		started <- struct{}{}
+		<-release
+		return nil
+	}}
+	if err := s.Register(blocking, "@hourly"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	fire := func() {
+		s.mu.Lock()
+		s.find("slow").next = time.Now().Add(-time.Second)
+		s.mu.Unlock()
+		s.runDue(time.Now())
+	}
+
+	fire()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not start")
+	}
+
+	fire()
+	if err := s.RunNow("slow"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("RunNow() while running error = %v, want ErrJobRunning", err)
+	}
+	if !status(t, s, "slow").Running {
+		t.Error("status does not report the job as running")
+	}
+
+	close(release)
+	s.Stop()
+
+	if got := runs.Load(); got != 1 {
+		t.Errorf("job ran %d times, want 1", got)
+	}
+	if next := status(t, s, "slow").NextRun; !next.After(time.Now()) {
+		t.Errorf("next run = %v, want a time after the skipped run", next)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"PakuchiBot/internal/mgclub"
 	"PakuchiBot/internal/repository"
@@ -20,6 +22,9 @@ var (
 	ErrSignInProgress = errors.New("sign in is already in progress")
 )
 
+// JobMGClubSign is the scheduler job that signs in the users whose scheduled time has come
+const JobMGClubSign = "mgclub_sign"
+
 type SignTask struct {
 	userRepo   *repository.UserRepository
 	signRepo   *repository.SignRepository
@@ -27,7 +32,13 @@ type SignTask struct {
 	crypto     *utils.TokenCrypto
 	bot        *zero.Ctx
 	isRunning  atomic.Bool
-	maxRetries int
+	maxRetries atomic.Int64
+	// concurrency is how many users are signed in at the same time
+	concurrency atomic.Int64
+	// defaultWindow applies to users without their own sign-in window
+	defaultWindow atomic.Pointer[SignWindow]
+	// loc is the time zone of sign-in windows
+	loc atomic.Pointer[time.Location]
 }
 
 func NewSignTask(
@@ -37,47 +48,82 @@ func NewSignTask(
 	crypto *utils.TokenCrypto,
 	bot *zero.Ctx,
 	maxRetries int,
+	concurrency int,
+	defaultWindow SignWindow,
+	loc *time.Location,
 ) *SignTask {
-	return &SignTask{
+	t := &SignTask{
 		userRepo:   userRepo,
 		signRepo:   signRepo,
 		notifyRepo: notifyRepo,
 		crypto:     crypto,
 		bot:        bot,
-		maxRetries: maxRetries,
 	}
+	t.Reconfigure(maxRetries, concurrency, defaultWindow, loc)
+	return t
+}
+
+// Job wraps Run for the scheduler
+func (t *SignTask) Job() Job {
+	return Job{Name: JobMGClubSign, Description: "毛吧自动签到", Run: t.Run}
+}
+
+// Reconfigure applies a new retry limit, concurrency, default sign-in window and time zone, times
+// already picked for today are kept and a run in progress keeps its number of workers
+func (t *SignTask) Reconfigure(maxRetries, concurrency int, defaultWindow SignWindow, loc *time.Location) {
+	t.maxRetries.Store(int64(maxRetries))
+	t.concurrency.Store(int64(max(concurrency, 1)))
+	t.defaultWindow.Store(&defaultWindow)
+	t.loc.Store(loc)
+}
+
+// DefaultWindow returns the sign-in window used for users without their own
+func (t *SignTask) DefaultWindow() SignWindow {
+	return *t.defaultWindow.Load()
+}
+
+// schedule picks today's sign-in time for a user, falling back to the default window
+// when the stored one no longer parses
+func (t *SignTask) schedule(user repository.User, now time.Time) repository.SignSchedule {
+	loc := t.loc.Load()
+	at, err := ScheduleFor(user.SignWindow, t.DefaultWindow(), now, loc)
+	if err != nil {
+		log.Printf("user %s has an invalid sign-in window, using the default: %v", user.UserID, err)
+		at, _ = ScheduleFor("", t.DefaultWindow(), now, loc)
+	}
+	return repository.SignSchedule{UserID: user.UserID, ScheduledAt: at}
 }
 
-func (t *SignTask) Run(ctx context.Context) {
+// Run signs in every user whose scheduled time has come using up to concurrency workers, a failure
+// for a single user is reported to that user and doesn't fail the run
+func (t *SignTask) Run(ctx context.Context) error {
 	if !t.isRunning.CompareAndSwap(false, true) {
-		log.Printf("check-in tasks are in progress")
-		return
+		return ErrSignInProgress
 	}
 	defer t.isRunning.Store(false)
 
 	users, err := t.userRepo.GetAllUsers(ctx)
 	if err != nil {
-		log.Printf("failed to get user list: %v", err)
-		return
+		return fmt.Errorf("failed to get user list: %w", err)
 	}
 
-	userIDs := make([]string, len(users))
+	// only used for records that don't exist yet, so each user gets one scheduled time per day
+	now := time.Now()
+	schedules := make([]repository.SignSchedule, len(users))
 	for i, user := range users {
-		userIDs[i] = user.UserID
+		schedules[i] = t.schedule(user, now)
 	}
-	if err := t.signRepo.InitDailyRecords(ctx, userIDs); err != nil {
-		log.Printf("failed to initialize sign-in log: %v", err)
-		return
+	if err := t.signRepo.InitDailyRecords(ctx, schedules); err != nil {
+		return fmt.Errorf("failed to initialize sign-in log: %w", err)
 	}
 
-	records, err := t.signRepo.GetPendingRecords(ctx, t.maxRetries)
+	records, err := t.signRepo.GetPendingRecords(ctx, int(t.maxRetries.Load()))
 	if err != nil {
-		log.Printf("failed to get pending check-in records: %v", err)
-		return
+		return fmt.Errorf("failed to get pending check-in records: %w", err)
 	}
 
 	if len(records) == 0 {
-		return
+		return nil
 	}
 
 	userMap := make(map[string]repository.User)
@@ -85,41 +131,138 @@ func (t *SignTask) Run(ctx context.Context) {
 		userMap[user.UserID] = user
 	}
 
+	work := make(chan repository.SignRecord)
+	var wg sync.WaitGroup
+	for range min(int(t.concurrency.Load()), len(records)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range work {
+				t.signPending(ctx, userMap, record)
+			}
+		}()
+	}
+
+	// records not handed out before cancellation stay pending for the next run
+feed:
 	for _, record := range records {
 		select {
 		case <-ctx.Done():
-			log.Printf("check-in tasks are canceled")
-			return
-		default:
-			user, ok := userMap[record.UserID]
-			if !ok {
-				log.Printf("user %s not found", record.UserID)
-				continue
-			}
-			t.processSignRecord(ctx, user, record)
+			break feed
+		case work <- record:
 		}
 	}
+	close(work)
+	wg.Wait()
+
+	return ctx.Err()
 }
 
-func (t *SignTask) processSignRecord(ctx context.Context, user repository.User, record repository.SignRecord) {
+func (t *SignTask) signPending(ctx context.Context, userMap map[string]repository.User, record repository.SignRecord) {
+	user, ok := userMap[record.UserID]
+	if !ok {
+		log.Printf("user %s not found", record.UserID)
+		return
+	}
+	// the user has already been told to bind the token again, don't try or notify until they do
+	if user.TokenInvalidAt != nil {
+		t.markFailed(ctx, record, repository.SignStatusAbandoned, repository.SignFailureAuth, time.Time{})
+		return
+	}
+	t.processSignRecord(ctx, user, record)
+}
+
+// RunUser signs in a single user right away, regardless of today's status, retry count and token state
+func (t *SignTask) RunUser(ctx context.Context, userID string) error {
+	if !t.isRunning.CompareAndSwap(false, true) {
+		return ErrSignInProgress
+	}
+	defer t.isRunning.Store(false)
+
+	user, err := t.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := t.signRepo.InitDailyRecords(ctx, []repository.SignSchedule{t.schedule(*user, time.Now())}); err != nil {
+		return fmt.Errorf("failed to initialize sign-in log: %w", err)
+	}
+
+	record, err := t.signRepo.GetTodayRecord(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get today's sign-in record: %w", err)
+	}
+
+	return t.processSignRecord(ctx, *user, *record)
+}
+
+func (t *SignTask) processSignRecord(ctx context.Context, user repository.User, record repository.SignRecord) error {
 	token, err := t.crypto.Decrypt(user.Token)
 	if err != nil {
 		log.Printf("user %s token decryption failed: %v", user.UserID, err)
-		t.notifyUser(ctx, user.UserID, fmt.Sprintf("自动签到时 token 解密失败啦，请将错误信息反馈给管理员哦\n\n%v", err), nil)
-		t.signRepo.UpdateStatus(ctx, record.ID, repository.SignStatusFailed)
-		return
+		t.handleFailure(ctx, user, record, repository.SignFailureDecrypt, err)
+		return err
 	}
 
-	result, err := mgclub.ProcessSign(token)
+	result, err := mgclub.ProcessSign(ctx, token)
 	if err != nil {
 		log.Printf("user %s sign in failed: %v", user.UserID, err)
-		t.notifyUser(ctx, user.UserID, fmt.Sprintf("自动签到失败啦，请将错误信息反馈给管理员哦\n\n%v", err), nil)
-		t.signRepo.UpdateStatus(ctx, record.ID, repository.SignStatusFailed)
-		return
+		t.handleFailure(ctx, user, record, classifySignError(err), err)
+		return err
+	}
+
+	if err := t.signRepo.MarkSuccess(ctx, record.ID, result.Exp); err != nil {
+		log.Printf("failed to update sign-in record of user %s: %v", user.UserID, err)
+	}
+	// a manual run with a token that was flagged before proved it works again
+	if user.TokenInvalidAt != nil {
+		if err := t.userRepo.SetTokenInvalid(ctx, user.UserID, false); err != nil {
+			log.Printf("failed to clear token state of user %s: %v", user.UserID, err)
+		}
 	}
 
-	t.signRepo.UpdateStatus(ctx, record.ID, repository.SignStatusSuccess)
 	t.notifyUser(ctx, user.UserID, result.Message, result.ImageData)
+	return nil
+}
+
+// handleFailure gives up on failures that retrying can't fix and backs off on everything else,
+// the user only hears about those once the retries are used up
+func (t *SignTask) handleFailure(ctx context.Context, user repository.User, record repository.SignRecord, reason repository.SignFailureReason, err error) {
+	attempt := record.RetryCount + 1
+
+	switch {
+	case reason == repository.SignFailureAuth:
+		t.markFailed(ctx, record, repository.SignStatusAbandoned, reason, time.Time{})
+		if err := t.userRepo.SetTokenInvalid(ctx, user.UserID, true); err != nil {
+			log.Printf("failed to flag token of user %s: %v", user.UserID, err)
+		}
+		t.notifyUser(ctx, user.UserID, "你的毛吧 token 已经失效啦，自动签到已暂停\n\n请使用 "+zero.BotConfig.CommandPrefix+"255token <token值> 重新绑定，绑定后会自动恢复喵", nil)
+
+	case reason == repository.SignFailureDecrypt:
+		t.markFailed(ctx, record, repository.SignStatusAbandoned, reason, time.Time{})
+		t.notifyUser(ctx, user.UserID, fmt.Sprintf("自动签到时 token 解密失败啦，请将错误信息反馈给管理员哦\n\n%v", err), nil)
+
+	case !retryable(reason):
+		t.markFailed(ctx, record, repository.SignStatusAbandoned, reason, time.Time{})
+		t.notifyUser(ctx, user.UserID, fmt.Sprintf("自动签到失败啦，这个错误重试也不会好转，今天不会再自动重试了，可以使用 %s255sign 手动签到\n\n如果一直失败，请将错误信息反馈给管理员哦\n\n%v",
+			zero.BotConfig.CommandPrefix, err), nil)
+
+	case attempt >= int(t.maxRetries.Load()):
+		t.markFailed(ctx, record, repository.SignStatusFailed, reason, time.Now())
+		t.notifyUser(ctx, user.UserID, fmt.Sprintf("自动签到失败啦，已经尝试了 %d 次，今天不会再自动重试了，可以使用 %s255sign 手动签到\n\n如果一直失败，请将错误信息反馈给管理员哦\n\n%v",
+			attempt, zero.BotConfig.CommandPrefix, err), nil)
+
+	default:
+		delay := retryDelay(attempt)
+		log.Printf("user %s sign in failed (%s), retrying in %s", user.UserID, reason, delay.Round(time.Second))
+		t.markFailed(ctx, record, repository.SignStatusFailed, reason, time.Now().Add(delay))
+	}
+}
+
+func (t *SignTask) markFailed(ctx context.Context, record repository.SignRecord, status repository.SignStatus, reason repository.SignFailureReason, nextRetryAt time.Time) {
+	if err := t.signRepo.MarkFailed(ctx, record.ID, status, reason, nextRetryAt); err != nil {
+		log.Printf("failed to update sign-in record of user %s: %v", record.UserID, err)
+	}
 }
 
 func (t *SignTask) notifyUser(ctx context.Context, userID string, msg string, imageData []byte) {
@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"PakuchiBot/internal/mgclub"
+	"PakuchiBot/internal/repository"
+)
+
+const (
+	// retryBaseDelay is the wait after the first failed attempt, it doubles with every further one
+	retryBaseDelay = 5 * time.Minute
+	retryMaxDelay  = 2 * time.Hour
+)
+
+// retryDelay returns the backoff before the next attempt, half of it is random so users whose
+// sign-in failed in the same outage don't all retry at the same moment
+func retryDelay(attempt int) time.Duration {
+	delay := retryMaxDelay
+	if shift := attempt - 1; shift < 16 {
+		delay = min(retryBaseDelay<<max(shift, 0), retryMaxDelay)
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// classifySignError maps a mgclub.ProcessSign error to the reason stored in sign_records
+func classifySignError(err error) repository.SignFailureReason {
+	switch {
+	case errors.Is(err, mgclub.ErrUnauthorized):
+		return repository.SignFailureAuth
+	case errors.Is(err, mgclub.ErrServer):
+		return repository.SignFailureServer
+	case errors.Is(err, mgclub.ErrNetwork):
+		return repository.SignFailureNetwork
+	case errors.Is(err, mgclub.ErrRejected):
+		return repository.SignFailureRejected
+	case errors.Is(err, mgclub.ErrBadResponse):
+		return repository.SignFailureResponse
+	default:
+		return repository.SignFailureUnknown
+	}
+}
+
+// retryable reports whether another attempt may succeed. Only an invalid token, an undecryptable
+// one and an error code from MGClub are final, a response that couldn't be understood or an
+// unknown error may well be gone on the next attempt.
+func retryable(reason repository.SignFailureReason) bool {
+	switch reason {
+	case repository.SignFailureAuth, repository.SignFailureDecrypt, repository.SignFailureRejected:
+		return false
+	default:
+		return true
+	}
+}
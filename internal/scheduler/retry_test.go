@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"PakuchiBot/internal/mgclub"
+	"PakuchiBot/internal/repository"
+)
+
+func TestSignErrorRetry(t *testing.T) {
+	tests := []struct {
+		err       error
+		reason    repository.SignFailureReason
+		retryable bool
+	}{
+		{fmt.Errorf("%w: connection reset", mgclub.ErrNetwork), repository.SignFailureNetwork, true},
+		{fmt.Errorf("%w: status 502", mgclub.ErrServer), repository.SignFailureServer, true},
+		{fmt.Errorf("%w: code 401", mgclub.ErrUnauthorized), repository.SignFailureAuth, false},
+		{fmt.Errorf("%w: code 500 活动已结束", mgclub.ErrRejected), repository.SignFailureRejected, false},
+		{fmt.Errorf("%w: unexpected end of JSON input", mgclub.ErrBadResponse), repository.SignFailureResponse, true},
+		{errors.New("something else"), repository.SignFailureUnknown, true},
+	}
+
+	for _, tt := range tests {
+		reason := classifySignError(tt.err)
+		if reason != tt.reason {
+			t.Errorf("classifySignError(%v) = %s, want %s", tt.err, reason, tt.reason)
+		}
+		if got := retryable(reason); got != tt.retryable {
+			t.Errorf("retryable(%s) = %v, want %v", reason, got, tt.retryable)
+		}
+	}
+
+	if retryable(repository.SignFailureDecrypt) {
+		t.Error("a token that can't be decrypted is retried")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{
+		1:  retryBaseDelay,
+		2:  2 * retryBaseDelay,
+		3:  4 * retryBaseDelay,
+		10: retryMaxDelay,
+		64: retryMaxDelay,
+	} {
+		if got := retryDelay(attempt); got < want/2 || got > want {
+			t.Errorf("retryDelay(%d) = %s, want between %s and %s", attempt, got, want/2, want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSpec = errors.New("invalid cron expression")
+
+// Schedule yields the activation times of a job
+type Schedule interface {
+	// Next returns the first activation strictly after t, the zero time if there is none
+	Next(t time.Time) time.Time
+}
+
+// ParseSpec parses a job schedule in the time zone loc. It accepts standard five-field cron
+// expressions (minute hour day-of-month month day-of-week), the descriptors @yearly, @monthly,
+// @weekly, @daily and @hourly, and "@every <duration>". A "CRON_TZ=<zone> " prefix overrides loc.
+func ParseSpec(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidSpec)
+	}
+
+	if rest, ok := strings.CutPrefix(spec, "CRON_TZ="); ok {
+		zone, expr, _ := strings.Cut(rest, " ")
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidSpec, zone)
+		}
+		spec = strings.TrimSpace(expr)
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		return parseDescriptor(spec, loc)
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q, expected 5 fields but got %d", ErrInvalidSpec, spec, len(fields))
+	}
+
+	s := &cronSchedule{loc: loc}
+	var err error
+	if s.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, fmt.Errorf("%w: minute %v", ErrInvalidSpec, err)
+	}
+	if s.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, fmt.Errorf("%w: hour %v", ErrInvalidSpec, err)
+	}
+	if s.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, fmt.Errorf("%w: day of month %v", ErrInvalidSpec, err)
+	}
+	if s.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, fmt.Errorf("%w: month %v", ErrInvalidSpec, err)
+	}
+	if s.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, fmt.Errorf("%w: day of week %v", ErrInvalidSpec, err)
+	}
+
+	// 7 is another name for Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.hourStar = strings.HasPrefix(fields[1], "*")
+	s.domStar = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[2], "?")
+	s.dowStar = strings.HasPrefix(fields[4], "*") || strings.HasPrefix(fields[4], "?")
+
+	return s, nil
+}
+
+func parseDescriptor(spec string, loc *time.Location) (Schedule, error) {
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("%w: %q, @every needs a duration of at least 1s", ErrInvalidSpec, spec)
+		}
+		return everySchedule(d), nil
+	}
+
+	descriptors := map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+	expr, ok := descriptors[spec]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown descriptor %q", ErrInvalidSpec, spec)
+	}
+	return ParseSpec(expr, loc)
+}
+
+// everySchedule fires at a fixed interval from whenever it was last computed
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Second).Add(time.Duration(e))
+}
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteBounds = bounds{min: 0, max: 59}
+	hourBounds   = bounds{min: 0, max: 23}
+	domBounds    = bounds{min: 1, max: 31}
+	monthBounds  = bounds{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowBounds = bounds{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// parseField turns a comma separated list of *, n, a-b and their /step forms into a bit set
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		expr, stepText, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return 0, fmt.Errorf("%q has an invalid step", part)
+			}
+		}
+
+		var lo, hi int
+		switch {
+		case expr == "*" || expr == "?":
+			lo, hi = b.min, b.max
+		case strings.Contains(expr, "-"):
+			from, to, _ := strings.Cut(expr, "-")
+			var err error
+			if lo, err = b.value(from); err != nil {
+				return 0, err
+			}
+			if hi, err = b.value(to); err != nil {
+				return 0, err
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("%q ends before it starts", part)
+			}
+		default:
+			var err error
+			if lo, err = b.value(expr); err != nil {
+				return 0, err
+			}
+			// "5/15" means from 5 to the end in steps of 15
+			hi = lo
+			if hasStep {
+				hi = b.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func (b bounds) value(s string) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("%d is out of range %d-%d", v, b.min, b.max)
+	}
+	return v, nil
+}
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// a fixed hour runs once per day across DST changes, a wildcard hour follows the clock, as in cron(8)
+	hourStar bool
+	// when neither day field starts with * a day matching either one fires, as in crontab(5)
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+// searchLimit bounds the search for expressions that never match, such as February 30th
+const searchLimit = 5
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	origLoc := t.Location()
+	t = t.In(s.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + searchLimit
+
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc))
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc))
+			continue
+		}
+		if !s.hourStar && t.Minute() == 0 && s.skippedBefore(t) {
+			return t.In(origLoc)
+		}
+		// step in absolute time, time.Date would map an hour skipped by DST back onto an earlier one
+		if s.hour&(1<<uint(t.Hour())) == 0 || (!s.hourStar && repeated(t)) {
+			t = t.Add(-time.Duration(t.Minute()) * time.Minute).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t.In(origLoc)
+	}
+
+	return time.Time{}
+}
+
+// forward returns next, or an hour after t when a DST change normalized next to a time not after t
+func forward(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(-time.Duration(t.Minute()) * time.Minute).Add(time.Hour)
+}
+
+// skippedBefore reports whether a spring forward gap ending at t swallowed an hour the schedule
+// matches, the job then runs when the gap ends instead of being lost for the day
+func (s *cronSchedule) skippedBefore(t time.Time) bool {
+	before := t.Add(-time.Hour).Hour()
+	for h := (before + 1) % 24; h != t.Hour() && h != before; h = (h + 1) % 24 {
+		if s.hour&(1<<uint(h)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// repeated reports whether t lies in the second pass of an hour repeated by a fall back
+func repeated(t time.Time) bool {
+	return t.Add(-time.Hour).Hour() == t.Hour()
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
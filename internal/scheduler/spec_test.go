@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSpecRejectsInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"10-5 * * * *",
+		"1-x * * * *",
+		"* * * foo *",
+		"* * * * someday",
+		"@fortnightly",
+		"@every 500ms",
+		"@every soon",
+		"CRON_TZ=Mars/Olympus 0 0 * * *",
+	}
+
+	for _, spec := range specs {
+		if _, err := ParseSpec(spec, time.UTC); !errors.Is(err, ErrInvalidSpec) {
+			t.Errorf("ParseSpec(%q) error = %v, want ErrInvalidSpec", spec, err)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	utc := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+	ny := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, newYork)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		loc  *time.Location
+		from time.Time
+		want time.Time
+	}{
+		{"strictly after", "0 9 * * *", time.UTC, utc(2026, 10, 17, 9, 0), utc(2026, 10, 18, 9, 0)},
+		{"step", "*/15 * * * *", time.UTC, utc(2026, 10, 17, 10, 7), utc(2026, 10, 17, 10, 15)},
+		{"step from value", "5/20 * * * *", time.UTC, utc(2026, 10, 17, 10, 30), utc(2026, 10, 17, 10, 45)},
+		{"step wraps hour", "5/20 * * * *", time.UTC, utc(2026, 10, 17, 10, 50), utc(2026, 10, 17, 11, 5)},
+		{"list", "0 8,20 * * *", time.UTC, utc(2026, 10, 17, 9, 0), utc(2026, 10, 17, 20, 0)},
+		{"weekday range", "0 9-17 * * mon-fri", time.UTC, utc(2026, 10, 16, 18, 0), utc(2026, 10, 19, 9, 0)},
+		{"month names", "0 0 1 JAN,jul *", time.UTC, utc(2026, 2, 1, 0, 0), utc(2026, 7, 1, 0, 0)},
+		{"seven is sunday", "0 12 * * 7", time.UTC, utc(2026, 10, 17, 0, 0), utc(2026, 10, 18, 12, 0)},
+		{"descriptor", "@weekly", time.UTC, utc(2026, 10, 17, 0, 0), utc(2026, 10, 18, 0, 0)},
+		{"every", "@every 90s", time.UTC, utc(2026, 10, 17, 10, 0).Add(30500 * time.Millisecond), utc(2026, 10, 17, 10, 2)},
+		// 2026-10-02 is a Friday, the 13th follows it
+		{"dom or dow", "0 0 13 * fri", time.UTC, utc(2026, 10, 1, 0, 0), utc(2026, 10, 2, 0, 0)},
+		{"dom or dow by dom", "0 0 13 * fri", time.UTC, utc(2026, 10, 9, 0, 0), utc(2026, 10, 13, 0, 0)},
+		{"dom and star dow", "0 0 13 * *", time.UTC, utc(2026, 10, 1, 0, 0), utc(2026, 10, 13, 0, 0)},
+		{"star dom and dow", "0 0 * * fri", time.UTC, utc(2026, 10, 10, 0, 0), utc(2026, 10, 16, 0, 0)},
+		{"leap day", "0 0 29 2 *", time.UTC, utc(2026, 3, 1, 0, 0), utc(2028, 2, 29, 0, 0)},
+		{"never", "0 0 30 2 *", time.UTC, utc(2026, 1, 1, 0, 0), time.Time{}},
+		{"cron tz", "CRON_TZ=Asia/Tokyo 0 9 * * *", time.UTC, utc(2026, 10, 17, 1, 0), utc(2026, 10, 18, 0, 0)},
+		{"spring forward gap", "30 2 * * *", newYork, ny(2026, 3, 7, 3, 0), ny(2026, 3, 8, 3, 0)},
+		{"after spring forward", "30 2 * * *", newYork, ny(2026, 3, 8, 3, 0), ny(2026, 3, 9, 2, 30)},
+		{"spring forward hourly", "30 * * * *", newYork, ny(2026, 3, 8, 1, 30), ny(2026, 3, 8, 3, 30)},
+		{"fall back once", "30 1 * * *", newYork, ny(2026, 11, 1, 0, 0), ny(2026, 11, 1, 1, 30)},
+		{"fall back skips repeat", "30 1 * * *", newYork, ny(2026, 11, 1, 1, 30), ny(2026, 11, 2, 1, 30)},
+		{"fall back hourly repeats", "0 * * * *", newYork, ny(2026, 11, 1, 1, 0), ny(2026, 11, 1, 1, 0).Add(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSpec(tt.spec, tt.loc)
+			if err != nil {
+				t.Fatalf("ParseSpec(%q) error = %v", tt.spec, err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
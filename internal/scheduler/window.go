@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSignWindow = errors.New("invalid sign-in window")
+
+// SignWindow is a daily time range such as 08:00-09:00, the zero value means no window
+type SignWindow struct {
+	Start time.Duration // offset from midnight
+	End   time.Duration
+}
+
+// ParseSignWindow parses "HH:MM-HH:MM" within one day, an empty string yields the zero window
+func ParseSignWindow(s string) (SignWindow, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return SignWindow{}, nil
+	}
+
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return SignWindow{}, fmt.Errorf("%w: %q, expected HH:MM-HH:MM", ErrInvalidSignWindow, s)
+	}
+
+	start, err := parseClock(from)
+	if err != nil {
+		return SignWindow{}, fmt.Errorf("%w: %q, expected HH:MM-HH:MM", ErrInvalidSignWindow, s)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return SignWindow{}, fmt.Errorf("%w: %q, expected HH:MM-HH:MM", ErrInvalidSignWindow, s)
+	}
+
+	if end <= start {
+		return SignWindow{}, fmt.Errorf("%w: %q, the end must be later than the start on the same day", ErrInvalidSignWindow, s)
+	}
+
+	return SignWindow{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w SignWindow) IsZero() bool {
+	return w.Start == 0 && w.End == 0
+}
+
+func (w SignWindow) String() string {
+	if w.IsZero() {
+		return ""
+	}
+	return formatClock(w.Start) + "-" + formatClock(w.End)
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// Pick returns a random time inside today's window in loc that is not before now,
+// now itself once the window has passed so a late start still signs in today
+func (w SignWindow) Pick(now time.Time, loc *time.Location) time.Time {
+	now = now.In(loc)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start := midnight.Add(w.Start)
+	end := midnight.Add(w.End)
+
+	if !now.Before(end) {
+		return now
+	}
+	if now.After(start) {
+		start = now
+	}
+
+	return start.Add(time.Duration(rand.Int63n(int64(end.Sub(start)))))
+}
+
+// ScheduleFor picks today's sign-in time for a user, the user's own window wins over the
+// default one and nil means sign in as soon as possible. Windows are wall clock times in loc.
+func ScheduleFor(userWindow string, defaultWindow SignWindow, now time.Time, loc *time.Location) (*time.Time, error) {
+	window, err := ParseSignWindow(userWindow)
+	if err != nil {
+		return nil, err
+	}
+	if window.IsZero() {
+		window = defaultWindow
+	}
+	if window.IsZero() {
+		return nil, nil
+	}
+
+	at := window.Pick(now, loc)
+	return &at, nil
+}
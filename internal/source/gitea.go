@@ -0,0 +1,327 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const giteaPageSize = 50
+
+// Gitea reads from a Gitea (or Forgejo) server through its v1 REST API
+type Gitea struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+func NewGitea(baseURL, token string, client *http.Client) *Gitea {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Gitea{
+		client:  client,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+	}
+}
+
+type giteaUser struct {
+	Login     string `json:"login"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type giteaLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type giteaIssue struct {
+	Number    int          `json:"number"`
+	Title     string       `json:"title"`
+	Body      string       `json:"body"`
+	State     string       `json:"state"`
+	User      giteaUser    `json:"user"`
+	Labels    []giteaLabel `json:"labels"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	ClosedAt  *time.Time   `json:"closed_at"`
+	HTMLURL   string       `json:"html_url"`
+
+	// pull requests only
+	MergedAt     *time.Time `json:"merged_at"`
+	Additions    *int       `json:"additions"`
+	Deletions    *int       `json:"deletions"`
+	ChangedFiles *int       `json:"changed_files"`
+}
+
+func (s *Gitea) get(ctx context.Context, path string, query url.Values, out interface{}) (http.Header, error) {
+	header := http.Header{}
+	if s.token != "" {
+		header.Set("Authorization", "token "+s.token)
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v1%s", s.baseURL, path)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	return getJSON(ctx, s.client, endpoint, header, out)
+}
+
+func (s *Gitea) repoPath(owner, repo string) string {
+	return fmt.Sprintf("/repos/%s/%s", url.PathEscape(owner), url.PathEscape(repo))
+}
+
+func (s *Gitea) Commits(ctx context.Context, owner, repo string, since time.Time) ([]Commit, error) {
+	var result []Commit
+	var limitErr error
+	for page := 1; ; page++ {
+		var commits []struct {
+			SHA     string `json:"sha"`
+			HTMLURL string `json:"html_url"`
+			Commit  struct {
+				Message string `json:"message"`
+				Author  struct {
+					Name string    `json:"name"`
+					Date time.Time `json:"date"`
+				} `json:"author"`
+				Committer struct {
+					Date time.Time `json:"date"`
+				} `json:"committer"`
+			} `json:"commit"`
+		}
+
+		query := url.Values{}
+		query.Set("since", since.UTC().Format(time.RFC3339))
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(giteaPageSize))
+		query.Set("stat", "false")
+		if _, err := s.get(ctx, s.repoPath(owner, repo)+"/commits", query, &commits); err != nil {
+			return nil, err
+		}
+
+		// older servers ignore since, so stop at the first commit that is not newer
+		reachedOld := false
+		for _, commit := range commits {
+			if !commit.Commit.Committer.Date.After(since) {
+				reachedOld = true
+				break
+			}
+			result = append(result, Commit{
+				SHA:         commit.SHA,
+				Message:     commit.Commit.Message,
+				Author:      commit.Commit.Author.Name,
+				AuthoredAt:  commit.Commit.Author.Date,
+				CommittedAt: commit.Commit.Committer.Date,
+				URL:         commit.HTMLURL,
+			})
+		}
+		if reachedOld || len(commits) < giteaPageSize {
+			break
+		}
+		if page == MaxPages {
+			limitErr = &PageLimitError{}
+			break
+		}
+	}
+
+	reverse(result)
+	return result, limitErr
+}
+
+func (s *Gitea) Releases(ctx context.Context, owner, repo string, known func(Release) bool) ([]Release, error) {
+	var result []Release
+	var limitErr error
+	for page := 1; ; page++ {
+		var releases []struct {
+			ID        int64     `json:"id"`
+			TagName   string    `json:"tag_name"`
+			Name      string    `json:"name"`
+			Body      string    `json:"body"`
+			Author    giteaUser `json:"author"`
+			CreatedAt time.Time `json:"created_at"`
+			HTMLURL   string    `json:"html_url"`
+		}
+
+		query := url.Values{}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(giteaPageSize))
+		if _, err := s.get(ctx, s.repoPath(owner, repo)+"/releases", query, &releases); err != nil {
+			return nil, err
+		}
+
+		reachedKnown := false
+		for _, r := range releases {
+			release := Release{
+				ID:        r.ID,
+				Key:       strconv.FormatInt(r.ID, 10),
+				TagName:   r.TagName,
+				Name:      r.Name,
+				Body:      r.Body,
+				Author:    r.Author.Login,
+				AvatarURL: r.Author.AvatarURL,
+				CreatedAt: r.CreatedAt,
+				URL:       r.HTMLURL,
+			}
+			if known(release) {
+				reachedKnown = true
+				continue
+			}
+			result = append(result, release)
+		}
+		if reachedKnown || len(releases) < giteaPageSize {
+			break
+		}
+		if page == MaxPages {
+			limitErr = &PageLimitError{}
+			break
+		}
+	}
+
+	reverse(result)
+	return result, limitErr
+}
+
+func (s *Gitea) Issues(ctx context.Context, owner, repo string, since time.Time) ([]Issue, error) {
+	var result []Issue
+	var limitErr error
+	for page := 1; ; page++ {
+		var issues []giteaIssue
+
+		query := url.Values{}
+		query.Set("state", "all")
+		query.Set("type", "issues")
+		query.Set("since", since.UTC().Format(time.RFC3339))
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(giteaPageSize))
+		header, err := s.get(ctx, s.repoPath(owner, repo)+"/issues", query, &issues)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range issues {
+			if !issue.UpdatedAt.Before(since) {
+				result = append(result, issue.toIssue())
+			}
+		}
+		if len(issues) < giteaPageSize {
+			break
+		}
+		if page == MaxPages {
+			limitErr = &PageLimitError{Remaining: remaining(header, "X-Total-Count", page*giteaPageSize)}
+			break
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].UpdatedAt.Before(result[j].UpdatedAt)
+	})
+	return result, limitErr
+}
+
+func (s *Gitea) PullRequests(ctx context.Context, owner, repo string, since time.Time) ([]PullRequest, error) {
+	var result []PullRequest
+	var limitErr error
+	for page := 1; ; page++ {
+		var prs []giteaIssue
+
+		query := url.Values{}
+		query.Set("state", "all")
+		query.Set("sort", "recentupdate")
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(giteaPageSize))
+		if _, err := s.get(ctx, s.repoPath(owner, repo)+"/pulls", query, &prs); err != nil {
+			return nil, err
+		}
+
+		reachedOld := false
+		for _, pr := range prs {
+			if pr.UpdatedAt.Before(since) {
+				reachedOld = true
+				continue
+			}
+			result = append(result, pr.toPullRequest())
+		}
+		if reachedOld || len(prs) < giteaPageSize {
+			break
+		}
+		if page == MaxPages {
+			limitErr = &PageLimitError{}
+			break
+		}
+	}
+
+	reverse(result)
+	return result, limitErr
+}
+
+func (s *Gitea) PullRequest(ctx context.Context, owner, repo string, number int) (*PullRequest, error) {
+	var pr giteaIssue
+	if _, err := s.get(ctx, fmt.Sprintf("%s/pulls/%d", s.repoPath(owner, repo), number), nil, &pr); err != nil {
+		return nil, err
+	}
+	result := pr.toPullRequest()
+	return &result, nil
+}
+
+func (s *Gitea) DefaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if _, err := s.get(ctx, s.repoPath(owner, repo), nil, &info); err != nil {
+		return "", err
+	}
+	return info.DefaultBranch, nil
+}
+
+func (s *Gitea) CommitsURL(owner, repo, branch string) string {
+	return fmt.Sprintf("%s/%s/%s/commits/branch/%s", s.baseURL, owner, repo, branch)
+}
+
+func (s *Gitea) CompareURL(owner, repo, base, head string) string {
+	return fmt.Sprintf("%s/%s/%s/compare/%s...%s", s.baseURL, owner, repo, base, head)
+}
+
+func (i giteaIssue) toIssue() Issue {
+	labels := make([]Label, 0, len(i.Labels))
+	for _, label := range i.Labels {
+		labels = append(labels, Label{Name: label.Name, Color: label.Color})
+	}
+
+	issue := Issue{
+		Number:    i.Number,
+		Title:     i.Title,
+		Body:      i.Body,
+		State:     i.State,
+		Author:    i.User.Login,
+		AvatarURL: i.User.AvatarURL,
+		Labels:    labels,
+		CreatedAt: i.CreatedAt,
+		UpdatedAt: i.UpdatedAt,
+		URL:       i.HTMLURL,
+	}
+	if i.ClosedAt != nil {
+		issue.ClosedAt = *i.ClosedAt
+	}
+	return issue
+}
+
+func (i giteaIssue) toPullRequest() PullRequest {
+	pr := PullRequest{Issue: i.toIssue()}
+	if i.MergedAt != nil {
+		pr.MergedAt = *i.MergedAt
+	}
+	if i.Additions != nil && i.Deletions != nil && i.ChangedFiles != nil {
+		pr.HasDiffStat = true
+		pr.Additions = *i.Additions
+		pr.Deletions = *i.Deletions
+		pr.ChangedFiles = *i.ChangedFiles
+	}
+	return pr
+}
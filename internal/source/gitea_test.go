@@ -0,0 +1,274 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var pollSince = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return data
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("failed to encode response: %v", err)
+	}
+}
+
+// giteaPage slices items the way Gitea pages with page and limit
+func giteaPage[T any](t *testing.T, r *http.Request, items []T) []T {
+	t.Helper()
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page < 1 || limit != giteaPageSize {
+		t.Errorf("unexpected paging query %q", r.URL.RawQuery)
+		return nil
+	}
+
+	start := min((page-1)*limit, len(items))
+	end := min(start+limit, len(items))
+	return items[start:end]
+}
+
+type giteaCommitJSON struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name string    `json:"name"`
+			Date time.Time `json:"date"`
+		} `json:"author"`
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+}
+
+// giteaCommits returns n commits newest first, one minute apart and ending at newest
+func giteaCommits(n int, newest time.Time) []giteaCommitJSON {
+	commits := make([]giteaCommitJSON, n)
+	for i := range commits {
+		date := newest.Add(-time.Duration(i) * time.Minute)
+		commits[i].SHA = fmt.Sprintf("%040d", n-i)
+		commits[i].HTMLURL = "https://gitea.example.com/octo/demo/commit/" + commits[i].SHA
+		commits[i].Commit.Message = fmt.Sprintf("commit %d", n-i)
+		commits[i].Commit.Author.Name = "alice"
+		commits[i].Commit.Author.Date = date
+		commits[i].Commit.Committer.Date = date
+	}
+	return commits
+}
+
+func TestGiteaCommits(t *testing.T) {
+	tests := []struct {
+		name      string
+		commits   []giteaCommitJSON
+		want      int
+		wantLimit bool
+	}{
+		{
+			name:    "single page",
+			commits: giteaCommits(3, pollSince.Add(time.Hour)),
+			want:    3,
+		},
+		{
+			name:    "second page",
+			commits: giteaCommits(giteaPageSize+7, pollSince.Add(24*time.Hour)),
+			want:    giteaPageSize + 7,
+		},
+		{
+			// older servers ignore since and keep listing history
+			name:    "stops at commits before since",
+			commits: giteaCommits(giteaPageSize*3, pollSince.Add(30*time.Minute)),
+			want:    30,
+		},
+		{
+			name:      "page limit",
+			commits:   giteaCommits(giteaPageSize*(MaxPages+1), pollSince.Add(30*24*time.Hour)),
+			want:      giteaPageSize * MaxPages,
+			wantLimit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/repos/octo/demo/commits" {
+					http.NotFound(w, r)
+					return
+				}
+				if got := r.Header.Get("Authorization"); got != "token secret" {
+					t.Errorf("Authorization = %q", got)
+				}
+				if got := r.URL.Query().Get("since"); got != "2026-03-01T00:00:00Z" {
+					t.Errorf("since = %q", got)
+				}
+				writeJSON(t, w, giteaPage(t, r, tt.commits))
+			}))
+			defer server.Close()
+
+			commits, err := NewGitea(server.URL+"/", "secret", server.Client()).Commits(context.Background(), "octo", "demo", pollSince)
+
+			var limit *PageLimitError
+			if tt.wantLimit {
+				if !errors.As(err, &limit) || limit.Oldest {
+					t.Fatalf("err = %v, want a page limit on a newest first list", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(commits) != tt.want {
+				t.Fatalf("got %d commits, want %d", len(commits), tt.want)
+			}
+			for i := 1; i < len(commits); i++ {
+				if !commits[i-1].CommittedAt.Before(commits[i].CommittedAt) {
+					t.Fatalf("commits are not oldest first at %d", i)
+				}
+			}
+			for _, commit := range commits {
+				if !commit.CommittedAt.After(pollSince) {
+					t.Errorf("commit %s at %v is not after since", commit.SHA, commit.CommittedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestGiteaIssues(t *testing.T) {
+	var issues []map[string]interface{}
+	for i := 1; i <= giteaPageSize+5; i++ {
+		issues = append(issues, map[string]interface{}{
+			"number":     i,
+			"title":      fmt.Sprintf("issue %d", i),
+			"state":      "open",
+			"user":       map[string]string{"login": "alice"},
+			"created_at": pollSince.Add(-time.Hour),
+			// listed newest first, the first one is older than since
+			"updated_at": pollSince.Add(time.Duration(giteaPageSize+3-i) * time.Minute),
+			"html_url":   fmt.Sprintf("https://gitea.example.com/octo/demo/issues/%d", i),
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/api/v1/repos/octo/demo/issues" || query.Get("type") != "issues" || query.Get("state") != "all" {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(t, w, giteaPage(t, r, issues))
+	}))
+	defer server.Close()
+
+	got, err := NewGitea(server.URL, "", server.Client()).Issues(context.Background(), "octo", "demo", pollSince)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// updated_at runs from since+52m down to since-2m, the last two are too old
+	if len(got) != giteaPageSize+3 {
+		t.Fatalf("got %d issues, want %d", len(got), giteaPageSize+3)
+	}
+	for i, issue := range got {
+		if issue.UpdatedAt.Before(pollSince) {
+			t.Errorf("issue #%d updated at %v before since", issue.Number, issue.UpdatedAt)
+		}
+		if i > 0 && issue.UpdatedAt.Before(got[i-1].UpdatedAt) {
+			t.Errorf("issues are not sorted by update time at %d", i)
+		}
+	}
+	if got[0].Number != giteaPageSize+3 || !got[0].UpdatedAt.Equal(pollSince) {
+		t.Errorf("first issue = #%d at %v, want the one updated exactly at since", got[0].Number, got[0].UpdatedAt)
+	}
+}
+
+func TestGiteaPullRequestMapping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/octo/demo/pulls/7" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(readFixture(t, "gitea_pull.json"))
+	}))
+	defer server.Close()
+
+	pr, err := NewGitea(server.URL, "", server.Client()).PullRequest(context.Background(), "octo", "demo", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged := time.Date(2026, 3, 2, 2, 30, 0, 0, time.UTC)
+	want := PullRequest{
+		Issue: Issue{
+			Number:    7,
+			Title:     "Add dark mode",
+			Body:      "Closes #3",
+			State:     "closed",
+			Author:    "alice",
+			AvatarURL: "https://gitea.example.com/avatars/alice",
+			Labels:    []Label{{Name: "enhancement", Color: "a2eeef"}},
+			CreatedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt: merged,
+			ClosedAt:  merged,
+			URL:       "https://gitea.example.com/octo/demo/pulls/7",
+		},
+		MergedAt:     merged,
+		HasDiffStat:  true,
+		Additions:    120,
+		Deletions:    8,
+		ChangedFiles: 5,
+	}
+	assertPullRequest(t, *pr, want)
+	if !pr.Merged() {
+		t.Error("pull request is not reported as merged")
+	}
+}
+
+func assertPullRequest(t *testing.T, got, want PullRequest) {
+	t.Helper()
+
+	times := []struct {
+		name      string
+		got, want time.Time
+	}{
+		{"CreatedAt", got.CreatedAt, want.CreatedAt},
+		{"UpdatedAt", got.UpdatedAt, want.UpdatedAt},
+		{"ClosedAt", got.ClosedAt, want.ClosedAt},
+		{"MergedAt", got.MergedAt, want.MergedAt},
+	}
+	for _, tt := range times {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	got.CreatedAt, got.UpdatedAt, got.ClosedAt, got.MergedAt = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	want.CreatedAt, want.UpdatedAt, want.ClosedAt, want.MergedAt = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pull request = %+v, want %+v", got, want)
+	}
+}
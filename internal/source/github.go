@@ -0,0 +1,283 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// Github reads from github.com or a GitHub Enterprise server
+type Github struct {
+	client *github.Client
+	webURL string
+}
+
+// NewGithub wraps a go-github client, webURL is the browser address of the server
+func NewGithub(client *github.Client, webURL string) *Github {
+	if webURL == "" {
+		webURL = "https://github.com"
+	}
+	return &Github{
+		client: client,
+		webURL: strings.TrimRight(webURL, "/"),
+	}
+}
+
+func (s *Github) Commits(ctx context.Context, owner, repo string, since time.Time) ([]Commit, error) {
+	opts := &github.CommitsListOptions{
+		Since: since,
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	commits := make([]Commit, 0)
+	var limitErr error
+	for page := 1; ; page++ {
+		result, resp, err := s.client.Repositories.ListCommits(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range result {
+			if commit.Commit == nil || commit.Commit.Message == nil {
+				continue
+			}
+			commits = append(commits, Commit{
+				SHA:         commit.GetSHA(),
+				Message:     commit.Commit.GetMessage(),
+				Author:      commit.Commit.Author.GetName(),
+				AuthoredAt:  commit.Commit.Author.GetDate(),
+				CommittedAt: commit.Commit.Committer.GetDate(),
+				URL:         commit.GetHTMLURL(),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		if page == MaxPages {
+			limitErr = &PageLimitError{Remaining: remainingPages(resp) * opts.PerPage}
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	reverse(commits)
+	return commits, limitErr
+}
+
+func (s *Github) Releases(ctx context.Context, owner, repo string, known func(Release) bool) ([]Release, error) {
+	opts := &github.ListOptions{
+		PerPage: 10,
+	}
+
+	releases := make([]Release, 0)
+	var limitErr error
+	for page := 1; ; page++ {
+		result, resp, err := s.client.Repositories.ListReleases(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		reachedKnown := false
+		for _, r := range result {
+			release := GithubRelease(r)
+			if known(release) {
+				reachedKnown = true
+				continue
+			}
+			releases = append(releases, release)
+		}
+		if resp.NextPage == 0 || reachedKnown {
+			break
+		}
+		if page == MaxPages {
+			limitErr = &PageLimitError{}
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	reverse(releases)
+	return releases, limitErr
+}
+
+func (s *Github) Issues(ctx context.Context, owner, repo string, since time.Time) ([]Issue, error) {
+	opts := &github.IssueListByRepoOptions{
+		Since:     since,
+		State:     "all",
+		Sort:      "updated",
+		Direction: "asc",
+		ListOptions: github.ListOptions{
+			PerPage: 50,
+		},
+	}
+
+	issues := make([]Issue, 0)
+	var limitErr error
+	for page := 1; ; page++ {
+		result, resp, err := s.client.Issues.ListByRepo(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range result {
+			if issue.IsPullRequest() || issue.GetUpdatedAt().Before(since) {
+				continue
+			}
+			issues = append(issues, GithubIssue(issue))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		if page == MaxPages {
+			// listed by update time ascending, the next poll continues after the last one
+			limitErr = &PageLimitError{Remaining: remainingPages(resp) * opts.PerPage, Oldest: true}
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return issues, limitErr
+}
+
+func (s *Github) PullRequests(ctx context.Context, owner, repo string, since time.Time) ([]PullRequest, error) {
+	opts := &github.PullRequestListOptions{
+		State:     "all",
+		Sort:      "updated",
+		Direction: "desc",
+		ListOptions: github.ListOptions{
+			PerPage: 20,
+		},
+	}
+
+	// pull requests cannot be listed since a time, stop paging once older updates show up
+	prs := make([]PullRequest, 0)
+	var limitErr error
+	for page := 1; ; page++ {
+		result, resp, err := s.client.PullRequests.List(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		reachedOld := false
+		for _, pr := range result {
+			if pr.GetUpdatedAt().Before(since) {
+				reachedOld = true
+				continue
+			}
+			prs = append(prs, GithubPullRequest(pr))
+		}
+		if resp.NextPage == 0 || reachedOld {
+			break
+		}
+		if page == MaxPages {
+			limitErr = &PageLimitError{}
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	reverse(prs)
+	return prs, limitErr
+}
+
+func (s *Github) PullRequest(ctx context.Context, owner, repo string, number int) (*PullRequest, error) {
+	pr, _, err := s.client.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	result := GithubPullRequest(pr)
+	return &result, nil
+}
+
+func (s *Github) DefaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	info, _, err := s.client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return "", err
+	}
+	return info.GetDefaultBranch(), nil
+}
+
+func (s *Github) CommitsURL(owner, repo, branch string) string {
+	return fmt.Sprintf("%s/%s/%s/commits/%s", s.webURL, owner, repo, branch)
+}
+
+func (s *Github) CompareURL(owner, repo, base, head string) string {
+	return fmt.Sprintf("%s/%s/%s/compare/%.12s...%.12s", s.webURL, owner, repo, base, head)
+}
+
+// remainingPages counts the pages after the current one from the Link header
+func remainingPages(resp *github.Response) int {
+	if resp.LastPage < resp.NextPage {
+		return 0
+	}
+	return resp.LastPage - resp.NextPage + 1
+}
+
+// GithubRelease converts a go-github release, also used for webhook payloads
+func GithubRelease(release *github.RepositoryRelease) Release {
+	return Release{
+		ID:        release.GetID(),
+		Key:       strconv.FormatInt(release.GetID(), 10),
+		TagName:   release.GetTagName(),
+		Name:      release.GetName(),
+		Body:      release.GetBody(),
+		Author:    release.GetAuthor().GetLogin(),
+		AvatarURL: release.GetAuthor().GetAvatarURL(),
+		CreatedAt: release.GetCreatedAt().Time,
+		URL:       release.GetHTMLURL(),
+	}
+}
+
+// GithubIssue converts a go-github issue, also used for webhook payloads
+func GithubIssue(issue *github.Issue) Issue {
+	return Issue{
+		Number:    issue.GetNumber(),
+		Title:     issue.GetTitle(),
+		Body:      issue.GetBody(),
+		State:     issue.GetState(),
+		Author:    issue.GetUser().GetLogin(),
+		AvatarURL: issue.GetUser().GetAvatarURL(),
+		Labels:    githubLabels(issue.Labels),
+		CreatedAt: issue.GetCreatedAt(),
+		UpdatedAt: issue.GetUpdatedAt(),
+		ClosedAt:  issue.GetClosedAt(),
+		URL:       issue.GetHTMLURL(),
+	}
+}
+
+// GithubPullRequest converts a go-github pull request, also used for webhook payloads
+func GithubPullRequest(pr *github.PullRequest) PullRequest {
+	return PullRequest{
+		Issue: Issue{
+			Number:    pr.GetNumber(),
+			Title:     pr.GetTitle(),
+			Body:      pr.GetBody(),
+			State:     pr.GetState(),
+			Author:    pr.GetUser().GetLogin(),
+			AvatarURL: pr.GetUser().GetAvatarURL(),
+			Labels:    githubLabels(pr.Labels),
+			CreatedAt: pr.GetCreatedAt(),
+			UpdatedAt: pr.GetUpdatedAt(),
+			ClosedAt:  pr.GetClosedAt(),
+			URL:       pr.GetHTMLURL(),
+		},
+		MergedAt:     pr.GetMergedAt(),
+		HasDiffStat:  pr.Additions != nil,
+		Additions:    pr.GetAdditions(),
+		Deletions:    pr.GetDeletions(),
+		ChangedFiles: pr.GetChangedFiles(),
+	}
+}
+
+func githubLabels(labels []*github.Label) []Label {
+	result := make([]Label, 0, len(labels))
+	for _, label := range labels {
+		result = append(result, Label{
+			Name:  label.GetName(),
+			Color: label.GetColor(),
+		})
+	}
+	return result
+}
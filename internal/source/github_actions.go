@@ -0,0 +1,142 @@
+package source
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+type WorkflowRun struct {
+	ID         int64
+	WorkflowID int64
+	Name       string
+	RunNumber  int
+	Branch     string
+	HeadSHA    string
+	Conclusion string // success, failure, cancelled, timed_out, ...
+	Actor      string
+	URL        string
+	UpdatedAt  time.Time
+}
+
+// Failed reports whether the run ended in a state that needs attention
+func (r WorkflowRun) Failed() bool {
+	switch r.Conclusion {
+	case "failure", "timed_out", "startup_failure":
+		return true
+	}
+	return false
+}
+
+// WorkflowSource is implemented by sources that run CI workflows
+type WorkflowSource interface {
+	// WorkflowRuns returns completed runs on branch updated after since, oldest first
+	WorkflowRuns(ctx context.Context, owner, repo, branch string, since time.Time) ([]WorkflowRun, error)
+	// PreviousRun returns the completed run of the same workflow and branch before run, nil when there is none
+	PreviousRun(ctx context.Context, owner, repo string, run WorkflowRun) (*WorkflowRun, error)
+	// FailedJobs returns the names of the failed jobs of a run
+	FailedJobs(ctx context.Context, owner, repo string, runID int64) ([]string, error)
+}
+
+func (s *Github) WorkflowRuns(ctx context.Context, owner, repo, branch string, since time.Time) ([]WorkflowRun, error) {
+	opts := &github.ListWorkflowRunsOptions{
+		Branch: branch,
+		Status: "completed",
+		ListOptions: github.ListOptions{
+			PerPage: 30,
+		},
+	}
+
+	runs := make([]WorkflowRun, 0)
+	var limitErr error
+	for page := 1; ; page++ {
+		result, resp, err := s.client.Actions.ListRepositoryWorkflowRuns(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		reachedOld := false
+		for _, run := range result.WorkflowRuns {
+			if !run.GetUpdatedAt().After(since) {
+				reachedOld = true
+				continue
+			}
+			runs = append(runs, GithubWorkflowRun(run))
+		}
+		if resp.NextPage == 0 || reachedOld {
+			break
+		}
+		if page == MaxPages {
+			limitErr = &PageLimitError{}
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	reverse(runs)
+	return runs, limitErr
+}
+
+func (s *Github) PreviousRun(ctx context.Context, owner, repo string, run WorkflowRun) (*WorkflowRun, error) {
+	opts := &github.ListWorkflowRunsOptions{
+		Branch: run.Branch,
+		Status: "completed",
+		ListOptions: github.ListOptions{
+			PerPage: 10,
+		},
+	}
+
+	result, _, err := s.client.Actions.ListWorkflowRunsByID(ctx, owner, repo, run.WorkflowID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// runs are listed newest first
+	for _, candidate := range result.WorkflowRuns {
+		if candidate.GetRunNumber() < run.RunNumber {
+			previous := GithubWorkflowRun(candidate)
+			return &previous, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *Github) FailedJobs(ctx context.Context, owner, repo string, runID int64) ([]string, error) {
+	opts := &github.ListWorkflowJobsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	jobs, _, err := s.client.Actions.ListWorkflowJobs(ctx, owner, repo, runID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for _, job := range jobs.Jobs {
+		switch job.GetConclusion() {
+		case "failure", "timed_out":
+			names = append(names, job.GetName())
+		}
+	}
+
+	return names, nil
+}
+
+// GithubWorkflowRun converts a go-github workflow run, also used for webhook payloads
+func GithubWorkflowRun(run *github.WorkflowRun) WorkflowRun {
+	return WorkflowRun{
+		ID:         run.GetID(),
+		WorkflowID: run.GetWorkflowID(),
+		Name:       run.GetName(),
+		RunNumber:  run.GetRunNumber(),
+		Branch:     run.GetHeadBranch(),
+		HeadSHA:    run.GetHeadSHA(),
+		Conclusion: run.GetConclusion(),
+		Actor:      run.GetActor().GetLogin(),
+		URL:        run.GetHTMLURL(),
+		UpdatedAt:  run.GetUpdatedAt().Time,
+	}
+}
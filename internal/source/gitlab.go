@@ -0,0 +1,319 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const gitlabPageSize = 50
+
+// Gitlab reads from gitlab.com or a self-hosted GitLab through its v4 REST API.
+// Owner may be a nested group path such as "group/subgroup".
+type Gitlab struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+func NewGitlab(baseURL, token string, client *http.Client) *Gitlab {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if baseURL == "" {
+		baseURL = "https://gitlab.com"
+	}
+	return &Gitlab{
+		client:  client,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+	}
+}
+
+type gitlabUser struct {
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type gitlabIssue struct {
+	IID         int        `json:"iid"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state"` // opened, closed, merged, locked
+	Author      gitlabUser `json:"author"`
+	Labels      []string   `json:"labels"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	MergedAt    *time.Time `json:"merged_at"`
+	WebURL      string     `json:"web_url"`
+}
+
+func (s *Gitlab) get(ctx context.Context, path string, query url.Values, out interface{}) (http.Header, error) {
+	header := http.Header{}
+	if s.token != "" {
+		header.Set("PRIVATE-TOKEN", s.token)
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v4%s", s.baseURL, path)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	return getJSON(ctx, s.client, endpoint, header, out)
+}
+
+func (s *Gitlab) projectPath(owner, repo string) string {
+	return "/projects/" + url.PathEscape(owner+"/"+repo)
+}
+
+// nextPage reads GitLab's X-Next-Page header, 0 when there are no more pages
+func nextPage(header http.Header) int {
+	page, _ := strconv.Atoi(header.Get("X-Next-Page"))
+	return page
+}
+
+func (s *Gitlab) Commits(ctx context.Context, owner, repo string, since time.Time) ([]Commit, error) {
+	var result []Commit
+	var limitErr error
+	page := 1
+	for i := 1; page != 0; i++ {
+		var commits []struct {
+			ID            string    `json:"id"`
+			Message       string    `json:"message"`
+			AuthorName    string    `json:"author_name"`
+			AuthoredDate  time.Time `json:"authored_date"`
+			CommittedDate time.Time `json:"committed_date"`
+			WebURL        string    `json:"web_url"`
+		}
+
+		query := url.Values{}
+		query.Set("since", since.UTC().Format(time.RFC3339))
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(gitlabPageSize))
+		header, err := s.get(ctx, s.projectPath(owner, repo)+"/repository/commits", query, &commits)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, commit := range commits {
+			result = append(result, Commit{
+				SHA:         commit.ID,
+				Message:     commit.Message,
+				Author:      commit.AuthorName,
+				AuthoredAt:  commit.AuthoredDate,
+				CommittedAt: commit.CommittedDate,
+				URL:         commit.WebURL,
+			})
+		}
+		page = nextPage(header)
+		if page != 0 && i == MaxPages {
+			limitErr = &PageLimitError{Remaining: remaining(header, "X-Total", i*gitlabPageSize)}
+			break
+		}
+	}
+
+	reverse(result)
+	return result, limitErr
+}
+
+func (s *Gitlab) Releases(ctx context.Context, owner, repo string, known func(Release) bool) ([]Release, error) {
+	var result []Release
+	var limitErr error
+	page := 1
+	for i := 1; page != 0; i++ {
+		var releases []struct {
+			TagName     string     `json:"tag_name"`
+			Name        string     `json:"name"`
+			Description string     `json:"description"`
+			Author      gitlabUser `json:"author"`
+			CreatedAt   time.Time  `json:"created_at"`
+			Links       struct {
+				Self string `json:"self"`
+			} `json:"_links"`
+		}
+
+		query := url.Values{}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(gitlabPageSize))
+		header, err := s.get(ctx, s.projectPath(owner, repo)+"/releases", query, &releases)
+		if err != nil {
+			return nil, err
+		}
+
+		reachedKnown := false
+		for _, r := range releases {
+			// GitLab releases have no numeric ID, the tag identifies them
+			release := Release{
+				Key:       r.TagName,
+				TagName:   r.TagName,
+				Name:      r.Name,
+				Body:      r.Description,
+				Author:    r.Author.Username,
+				AvatarURL: r.Author.AvatarURL,
+				CreatedAt: r.CreatedAt,
+				URL:       r.Links.Self,
+			}
+			if known(release) {
+				reachedKnown = true
+				continue
+			}
+			result = append(result, release)
+		}
+		if reachedKnown {
+			break
+		}
+		page = nextPage(header)
+		if page != 0 && i == MaxPages {
+			limitErr = &PageLimitError{}
+			break
+		}
+	}
+
+	reverse(result)
+	return result, limitErr
+}
+
+func (s *Gitlab) Issues(ctx context.Context, owner, repo string, since time.Time) ([]Issue, error) {
+	var result []Issue
+	var limitErr error
+	page := 1
+	for i := 1; page != 0; i++ {
+		var issues []gitlabIssue
+
+		query := url.Values{}
+		query.Set("updated_after", since.UTC().Format(time.RFC3339))
+		query.Set("order_by", "updated_at")
+		query.Set("sort", "asc")
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(gitlabPageSize))
+		header, err := s.get(ctx, s.projectPath(owner, repo)+"/issues", query, &issues)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range issues {
+			if !issue.UpdatedAt.Before(since) {
+				result = append(result, issue.toIssue())
+			}
+		}
+		page = nextPage(header)
+		if page != 0 && i == MaxPages {
+			// listed by update time ascending, the next poll continues after the last one
+			limitErr = &PageLimitError{Remaining: remaining(header, "X-Total", i*gitlabPageSize), Oldest: true}
+			break
+		}
+	}
+
+	return result, limitErr
+}
+
+func (s *Gitlab) PullRequests(ctx context.Context, owner, repo string, since time.Time) ([]PullRequest, error) {
+	var result []PullRequest
+	var limitErr error
+	page := 1
+	for i := 1; page != 0; i++ {
+		var mrs []gitlabIssue
+
+		query := url.Values{}
+		query.Set("updated_after", since.UTC().Format(time.RFC3339))
+		query.Set("order_by", "updated_at")
+		query.Set("sort", "asc")
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(gitlabPageSize))
+		header, err := s.get(ctx, s.projectPath(owner, repo)+"/merge_requests", query, &mrs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, mr := range mrs {
+			if !mr.UpdatedAt.Before(since) {
+				result = append(result, mr.toPullRequest())
+			}
+		}
+		page = nextPage(header)
+		if page != 0 && i == MaxPages {
+			limitErr = &PageLimitError{Remaining: remaining(header, "X-Total", i*gitlabPageSize), Oldest: true}
+			break
+		}
+	}
+
+	return result, limitErr
+}
+
+// PullRequest returns a merge request, GitLab only reports a changed file count so there is no diffstat
+func (s *Gitlab) PullRequest(ctx context.Context, owner, repo string, number int) (*PullRequest, error) {
+	var mr gitlabIssue
+	if _, err := s.get(ctx, fmt.Sprintf("%s/merge_requests/%d", s.projectPath(owner, repo), number), nil, &mr); err != nil {
+		return nil, err
+	}
+	result := mr.toPullRequest()
+	return &result, nil
+}
+
+func (s *Gitlab) DefaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if _, err := s.get(ctx, s.projectPath(owner, repo), nil, &info); err != nil {
+		return "", err
+	}
+	return info.DefaultBranch, nil
+}
+
+func (s *Gitlab) CommitsURL(owner, repo, branch string) string {
+	return fmt.Sprintf("%s/%s/%s/-/commits/%s", s.baseURL, owner, repo, branch)
+}
+
+func (s *Gitlab) CompareURL(owner, repo, base, head string) string {
+	return fmt.Sprintf("%s/%s/%s/-/compare/%s...%s", s.baseURL, owner, repo, base, head)
+}
+
+func (i gitlabIssue) toIssue() Issue {
+	labels := make([]Label, 0, len(i.Labels))
+	for _, name := range i.Labels {
+		labels = append(labels, Label{Name: name})
+	}
+
+	// normalize to the GitHub vocabulary used by filters and cards
+	state := i.State
+	switch state {
+	case "opened":
+		state = "open"
+	case "merged", "locked":
+		state = "closed"
+	}
+
+	issue := Issue{
+		Number:    i.IID,
+		Title:     i.Title,
+		Body:      i.Description,
+		State:     state,
+		Author:    i.Author.Username,
+		AvatarURL: i.Author.AvatarURL,
+		Labels:    labels,
+		CreatedAt: i.CreatedAt,
+		UpdatedAt: i.UpdatedAt,
+		URL:       i.WebURL,
+	}
+	if i.ClosedAt != nil {
+		issue.ClosedAt = *i.ClosedAt
+	}
+	return issue
+}
+
+func (i gitlabIssue) toPullRequest() PullRequest {
+	pr := PullRequest{Issue: i.toIssue()}
+	if i.MergedAt != nil {
+		pr.MergedAt = *i.MergedAt
+		// merged requests have no closed_at
+		if pr.ClosedAt.IsZero() {
+			pr.ClosedAt = *i.MergedAt
+		}
+	}
+	return pr
+}
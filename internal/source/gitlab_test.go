@@ -0,0 +1,227 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// gitlabServer serves items under path the way GitLab pages with page, per_page and X-Next-Page
+func gitlabServer[T any](t *testing.T, path string, items []T, check func(r *http.Request)) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != path {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("PRIVATE-TOKEN"); got != "secret" {
+			t.Errorf("PRIVATE-TOKEN = %q", got)
+		}
+		if check != nil {
+			check(r)
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		if page < 1 || perPage != gitlabPageSize {
+			t.Errorf("unexpected paging query %q", r.URL.RawQuery)
+		}
+
+		start := min((page-1)*perPage, len(items))
+		end := min(start+perPage, len(items))
+		if end < len(items) {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		}
+		w.Header().Set("X-Total", strconv.Itoa(len(items)))
+		writeJSON(t, w, items[start:end])
+	}))
+}
+
+func TestGitlabCommits(t *testing.T) {
+	commits := func(n int) []map[string]interface{} {
+		result := make([]map[string]interface{}, n)
+		for i := range result {
+			date := pollSince.Add(time.Duration(n-i) * time.Minute)
+			result[i] = map[string]interface{}{
+				"id":             fmt.Sprintf("%040d", n-i),
+				"message":        fmt.Sprintf("commit %d\n\nbody", n-i),
+				"author_name":    "bob",
+				"authored_date":  date.Add(-time.Hour),
+				"committed_date": date,
+				"web_url":        "https://gitlab.example.com/commit",
+			}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name          string
+		commits       []map[string]interface{}
+		want          int
+		wantRemaining int
+		wantLimit     bool
+	}{
+		{"empty", commits(0), 0, 0, false},
+		{"follows next page", commits(gitlabPageSize*2 + 1), gitlabPageSize*2 + 1, 0, false},
+		{"page limit", commits(gitlabPageSize*MaxPages + 20), gitlabPageSize * MaxPages, 20, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := gitlabServer(t, "/api/v4/projects/group%2Fsub%2Fdemo/repository/commits", tt.commits, func(r *http.Request) {
+				if got := r.URL.Query().Get("since"); got != "2026-03-01T00:00:00Z" {
+					t.Errorf("since = %q", got)
+				}
+			})
+			defer server.Close()
+
+			got, err := NewGitlab(server.URL, "secret", server.Client()).Commits(context.Background(), "group/sub", "demo", pollSince)
+
+			var limit *PageLimitError
+			if tt.wantLimit {
+				if !errors.As(err, &limit) || limit.Oldest || limit.Remaining != tt.wantRemaining {
+					t.Fatalf("err = %#v, want a page limit with %d remaining", err, tt.wantRemaining)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != tt.want {
+				t.Fatalf("got %d commits, want %d", len(got), tt.want)
+			}
+			for i := 1; i < len(got); i++ {
+				if !got[i-1].CommittedAt.Before(got[i].CommittedAt) {
+					t.Fatalf("commits are not oldest first at %d", i)
+				}
+			}
+			if len(got) > 0 && got[0].Author != "bob" {
+				t.Errorf("author = %q, want bob", got[0].Author)
+			}
+		})
+	}
+}
+
+func TestGitlabIssues(t *testing.T) {
+	issue := func(iid int, state string, updated time.Time, closed interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"iid":         iid,
+			"title":       fmt.Sprintf("issue %d", iid),
+			"description": "details",
+			"state":       state,
+			"author":      map[string]string{"username": "bob", "avatar_url": "https://gitlab.example.com/bob.png"},
+			"labels":      []string{"bug"},
+			"created_at":  pollSince.Add(-24 * time.Hour),
+			"updated_at":  updated,
+			"closed_at":   closed,
+			"web_url":     fmt.Sprintf("https://gitlab.example.com/group/sub/demo/-/issues/%d", iid),
+		}
+	}
+
+	var issues []map[string]interface{}
+	// updated_after is exclusive on GitLab, an issue updated exactly at since is filtered client side
+	issues = append(issues, issue(1, "opened", pollSince.Add(-time.Second), nil))
+	for i := 2; i <= gitlabPageSize+2; i++ {
+		issues = append(issues, issue(i, "opened", pollSince.Add(time.Duration(i)*time.Minute), nil))
+	}
+	closedAt := pollSince.Add(2 * time.Hour)
+	issues = append(issues, issue(99, "closed", closedAt, closedAt))
+
+	server := gitlabServer(t, "/api/v4/projects/group%2Fsub%2Fdemo/issues", issues, func(r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("updated_after") != "2026-03-01T00:00:00Z" || query.Get("order_by") != "updated_at" || query.Get("sort") != "asc" {
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+	})
+	defer server.Close()
+
+	got, err := NewGitlab(server.URL, "secret", server.Client()).Issues(context.Background(), "group/sub", "demo", pollSince)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != gitlabPageSize+2 {
+		t.Fatalf("got %d issues, want %d", len(got), gitlabPageSize+2)
+	}
+	if got[0].Number != 2 {
+		t.Errorf("first issue = #%d, want #2", got[0].Number)
+	}
+
+	first := got[0]
+	if first.State != "open" || first.Author != "bob" || first.Body != "details" || !first.ClosedAt.IsZero() {
+		t.Errorf("open issue mapped to %+v", first)
+	}
+	if len(first.Labels) != 1 || first.Labels[0] != (Label{Name: "bug"}) {
+		t.Errorf("labels = %+v", first.Labels)
+	}
+
+	last := got[len(got)-1]
+	if last.Number != 99 || last.State != "closed" || !last.ClosedAt.Equal(closedAt) {
+		t.Errorf("closed issue mapped to %+v", last)
+	}
+}
+
+func TestGitlabIssuesPageLimitResumes(t *testing.T) {
+	issues := make([]map[string]interface{}, gitlabPageSize*MaxPages+5)
+	for i := range issues {
+		issues[i] = map[string]interface{}{
+			"iid":        i + 1,
+			"state":      "opened",
+			"updated_at": pollSince.Add(time.Duration(i+1) * time.Second),
+		}
+	}
+
+	server := gitlabServer(t, "/api/v4/projects/octo%2Fdemo/issues", issues, nil)
+	defer server.Close()
+
+	got, err := NewGitlab(server.URL, "secret", server.Client()).Issues(context.Background(), "octo", "demo", pollSince)
+
+	var limit *PageLimitError
+	if !errors.As(err, &limit) || !limit.Oldest || limit.Remaining != 5 {
+		t.Fatalf("err = %#v, want a resumable page limit with 5 remaining", err)
+	}
+	if len(got) != gitlabPageSize*MaxPages || got[len(got)-1].Number != gitlabPageSize*MaxPages {
+		t.Fatalf("got %d issues, want the oldest %d", len(got), gitlabPageSize*MaxPages)
+	}
+}
+
+func TestGitlabMergeRequestMapping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fsub%2Fdemo/merge_requests/12" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(readFixture(t, "gitlab_merge_request.json"))
+	}))
+	defer server.Close()
+
+	pr, err := NewGitlab(server.URL, "", server.Client()).PullRequest(context.Background(), "group/sub", "demo", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged := time.Date(2026, 4, 11, 15, 20, 0, 0, time.UTC)
+	assertPullRequest(t, *pr, PullRequest{
+		Issue: Issue{
+			Number:    12,
+			Title:     "Draft: rework pipeline",
+			Body:      "Splits the build stage",
+			State:     "closed",
+			Author:    "bob",
+			AvatarURL: "https://gitlab.example.com/uploads/bob.png",
+			Labels:    []Label{{Name: "ci"}, {Name: "backend"}},
+			CreatedAt: time.Date(2026, 4, 10, 9, 0, 0, 0, time.UTC),
+			UpdatedAt: merged,
+			// merged requests have no closed_at, the merge time stands in
+			ClosedAt: merged,
+			URL:      "https://gitlab.example.com/group/sub/demo/-/merge_requests/12",
+		},
+		MergedAt: merged,
+	})
+}
@@ -0,0 +1,161 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaxPages bounds the pages fetched per list call so a long outage cannot turn into an endless crawl
+const MaxPages = 10
+
+// PageLimitError is returned together with the items read so far when a list call stopped at
+// MaxPages while the forge still had more pages
+type PageLimitError struct {
+	// Remaining estimates the unread items, 0 when the forge does not report a total
+	Remaining int
+	// Oldest is set when the list pages oldest first, the cursor can then move up to the last
+	// returned item and the next poll carries on. Otherwise the newest items were returned and
+	// the older ones are skipped.
+	Oldest bool
+}
+
+func (e *PageLimitError) Error() string {
+	if e.Remaining > 0 {
+		return fmt.Sprintf("stopped after %d pages with about %d items left", MaxPages, e.Remaining)
+	}
+	return fmt.Sprintf("stopped after %d pages with more items left", MaxPages)
+}
+
+type Label struct {
+	Name  string
+	Color string // hex without '#'
+}
+
+type Commit struct {
+	SHA         string
+	Message     string
+	Author      string
+	AuthoredAt  time.Time
+	CommittedAt time.Time
+	URL         string
+}
+
+type Release struct {
+	ID        int64  // 0 when the forge has no numeric release IDs
+	Key       string // stable identifier for de-duplication
+	TagName   string
+	Name      string
+	Body      string
+	Author    string
+	AvatarURL string
+	CreatedAt time.Time
+	URL       string
+}
+
+// Title returns the release name, falling back to the tag
+func (r Release) Title() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.TagName
+}
+
+type Issue struct {
+	Number    int
+	Title     string
+	Body      string
+	State     string // open, closed
+	Author    string
+	AvatarURL string
+	Labels    []Label
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ClosedAt  time.Time // zero while open
+	URL       string
+}
+
+type PullRequest struct {
+	Issue
+	MergedAt time.Time // zero unless merged
+
+	HasDiffStat  bool
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+}
+
+func (p PullRequest) Merged() bool {
+	return !p.MergedAt.IsZero()
+}
+
+// Source lists repository activity from a code hosting service.
+// All list results are ordered oldest first. A list stopped by MaxPages returns the items
+// it read together with a *PageLimitError.
+type Source interface {
+	// Commits returns commits on the default branch since the given time
+	Commits(ctx context.Context, owner, repo string, since time.Time) ([]Commit, error)
+	// Releases returns the releases for which known reports false, paging back until a known one shows up
+	Releases(ctx context.Context, owner, repo string, known func(Release) bool) ([]Release, error)
+	// Issues returns issues updated at or after since, excluding pull requests. Items updated in the
+	// same second as since come back again, callers drop repeats by their delivery key.
+	Issues(ctx context.Context, owner, repo string, since time.Time) ([]Issue, error)
+	// PullRequests returns pull requests updated at or after since, diffstats may be missing
+	PullRequests(ctx context.Context, owner, repo string, since time.Time) ([]PullRequest, error)
+	// PullRequest returns a single pull request including its diffstat when the forge provides one
+	PullRequest(ctx context.Context, owner, repo string, number int) (*PullRequest, error)
+	DefaultBranch(ctx context.Context, owner, repo string) (string, error)
+
+	CommitsURL(owner, repo, branch string) string
+	CompareURL(owner, repo, base, head string) string
+}
+
+// getJSON fetches url into out and returns the response headers for pagination
+func getJSON(ctx context.Context, client *http.Client, url string, header http.Header, out interface{}) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GET %s: unexpected status %d: %s", url, resp.StatusCode, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("GET %s: failed to decode response: %w", url, err)
+	}
+
+	return resp.Header, nil
+}
+
+// remaining subtracts the items read from a total count header, 0 when the header is missing
+func remaining(header http.Header, name string, read int) int {
+	total, err := strconv.Atoi(header.Get(name))
+	if err != nil || total <= read {
+		return 0
+	}
+	return total - read
+}
+
+func reverse[T any](items []T) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
@@ -1,24 +1,47 @@
 package storage
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
-	"sort"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
 	_ "modernc.org/sqlite"
 )
 
-var (
-	db     *sql.DB
-	sqlxDB *sqlx.DB
-)
+// pragmas are applied to every pooled connection, foreign_keys in particular is per connection in SQLite
+var pragmas = []string{
+	"busy_timeout(5000)",
+	"journal_mode(WAL)",
+	"synchronous(NORMAL)",
+	"foreign_keys(ON)",
+}
+
+// maxOpenConns allows concurrent readers under WAL, writers wait on busy_timeout
+const maxOpenConns = 4
 
+var db *sqlx.DB
+
+// InitDB opens the database shared by all repositories and applies pending migrations
 func InitDB(dbPath string) error {
+	if err := OpenDB(dbPath); err != nil {
+		return err
+	}
+
+	if _, err := MigrateUp(); err != nil {
+		db.Close()
+		return fmt.Errorf("failed on database migration: %w", err)
+	}
+
+	return nil
+}
+
+// OpenDB opens the database without applying migrations
+func OpenDB(dbPath string) error {
 	if strings.HasPrefix(dbPath, "~") {
 		home, err := getHomeDir()
 		if err != nil {
@@ -43,106 +66,55 @@ func InitDB(dbPath string) error {
 	}
 
 	var dbErr error
-	db, dbErr = sql.Open("sqlite", fmt.Sprintf("file:%s?_journal_mode=WAL&_synchronous=NORMAL&cache=shared", dbPath))
+	db, dbErr = sqlx.Open("sqlite", dsn(dbPath))
 	if dbErr != nil {
 		return fmt.Errorf("failed to open database connection: %w", dbErr)
 	}
 
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxOpenConns)
 
 	if err := db.Ping(); err != nil {
 		db.Close()
 		return fmt.Errorf("failed to test database connection: %w", err)
 	}
 
-	sqlxDB = sqlx.NewDb(db, "sqlite")
+	return nil
+}
 
-	if err := migrate(); err != nil {
-		db.Close()
-		return fmt.Errorf("failed on database migration: %w", err)
+// dsn builds the connection string, write transactions take the lock up front so they wait on busy_timeout
+// instead of failing when upgrading from a read lock. SQLite reads it as a URI, so the path is escaped
+// to keep a ?, # or % in a directory name from cutting it short.
+func dsn(dbPath string) string {
+	query := url.Values{}
+	for _, pragma := range pragmas {
+		query.Add("_pragma", pragma)
 	}
+	query.Set("_txlock", "immediate")
 
-	return nil
+	path := (&url.URL{Path: dbPath}).EscapedPath()
+	return (&url.URL{Scheme: "file", Opaque: path, RawQuery: query.Encode()}).String()
 }
 
 func GetDB() *sqlx.DB {
-	return sqlxDB
+	return db
 }
 
 func CloseDB() error {
-	if sqlxDB != nil {
-		sqlxDB.Close()
-	}
 	if db != nil {
 		return db.Close()
 	}
 	return nil
 }
 
-func migrate() error {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS schema_migrations (
-			version TEXT PRIMARY KEY,
-			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create migration log table: %w", err)
-	}
-
-	appliedVersions := make(map[string]bool)
-	rows, err := db.Query("SELECT version FROM schema_migrations")
-	if err != nil {
-		return fmt.Errorf("failed to query applied migrations: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var version string
-		if err := rows.Scan(&version); err != nil {
-			return fmt.Errorf("failed to read migration version: %w", err)
-		}
-		appliedVersions[version] = true
+// Optimize refreshes the query planner statistics and folds the WAL back into the database file
+func Optimize(ctx context.Context) error {
+	if _, err := db.ExecContext(ctx, "PRAGMA optimize"); err != nil {
+		return fmt.Errorf("failed to optimize database: %w", err)
 	}
-
-	files, err := filepath.Glob("migrations/*.sql")
-	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
-	}
-	sort.Strings(files)
-
-	for _, file := range files {
-		version := filepath.Base(file[:len(file)-4])
-		if appliedVersions[version] {
-			continue
-		}
-
-		content, err := os.ReadFile(file)
-		if err != nil {
-			return fmt.Errorf("failed to read migration file %s: %w", file, err)
-		}
-
-		tx, err := db.Begin()
-		if err != nil {
-			return fmt.Errorf("failed to begin transaction: %w", err)
-		}
-
-		if _, err := tx.Exec(string(content)); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to execute migration %s: %w", file, err)
-		}
-
-		if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", version); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to record migration version: %w", err)
-		}
-
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit transaction: %w", err)
-		}
+	if _, err := db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint database: %w", err)
 	}
-
 	return nil
 }
 
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenDBEscapesPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "what?#100%")
+	dbPath := filepath.Join(dir, "bot.db")
+
+	if err := InitDB(dbPath); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	t.Cleanup(func() { CloseDB() })
+
+	var mode string
+	if err := GetDB().Get(&mode, "PRAGMA journal_mode"); err != nil {
+		t.Fatalf("failed to read journal mode: %v", err)
+	}
+	if mode != "wal" {
+		t.Errorf("journal_mode = %q, want wal", mode)
+	}
+
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Errorf("database was not created at %s: %v", dbPath, err)
+	}
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrSchemaMismatch = errors.New("dump was exported from a different schema version")
+
+// Dump is a JSON snapshot of every table, encrypted tokens stay encrypted
+type Dump struct {
+	SchemaVersion string                      `json:"schema_version"`
+	ExportedAt    time.Time                   `json:"exported_at"`
+	Tables        map[string][]map[string]any `json:"tables"`
+}
+
+// dumpTables lists the user tables, the migration log and SQLite internals are left out
+func dumpTables(ctx context.Context) ([]string, error) {
+	var tables []string
+	query := `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table'
+		AND name NOT LIKE 'sqlite_%'
+		AND name != 'schema_migrations'
+		ORDER BY name
+	`
+	if err := db.SelectContext(ctx, &tables, query); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	return tables, nil
+}
+
+func tableColumns(ctx context.Context, table string) ([]string, error) {
+	var columns []string
+	if err := db.SelectContext(ctx, &columns, `SELECT name FROM pragma_table_info(?) ORDER BY cid`, table); err != nil {
+		return nil, fmt.Errorf("failed to list columns of %s: %w", table, err)
+	}
+	return columns, nil
+}
+
+func schemaVersion(ctx context.Context) (string, error) {
+	var version string
+	if err := db.GetContext(ctx, &version, `SELECT COALESCE(MAX(version), '') FROM schema_migrations`); err != nil {
+		return "", fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Export reads every table into a Dump
+func Export(ctx context.Context) (*Dump, error) {
+	version, err := schemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := dumpTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dump := &Dump{
+		SchemaVersion: version,
+		ExportedAt:    time.Now(),
+		Tables:        make(map[string][]map[string]any, len(tables)),
+	}
+
+	for _, table := range tables {
+		columns, err := tableColumns(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+
+		// "+column" drops the declared type, so datetimes come back exactly as stored instead of being parsed
+		selects := make([]string, len(columns))
+		for i, column := range columns {
+			selects[i] = "+" + quoteIdent(column) + " AS " + quoteIdent(column)
+		}
+
+		rows, err := db.QueryxContext(ctx, fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), quoteIdent(table)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read table %s: %w", table, err)
+		}
+
+		records := make([]map[string]any, 0)
+		for rows.Next() {
+			record := make(map[string]any, len(columns))
+			if err := rows.MapScan(record); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read row of %s: %w", table, err)
+			}
+			for column, value := range record {
+				if b, ok := value.([]byte); ok {
+					record[column] = string(b)
+				}
+			}
+			records = append(records, record)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read table %s: %w", table, err)
+		}
+		rows.Close()
+
+		dump.Tables[table] = records
+	}
+
+	return dump, nil
+}
+
+// Import replaces the contents of every table in the dump inside one transaction,
+// the dump must come from the same schema version
+func Import(ctx context.Context, dump *Dump) error {
+	version, err := schemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if dump.SchemaVersion != version {
+		return fmt.Errorf("%w: dump %q, database %q", ErrSchemaMismatch, dump.SchemaVersion, version)
+	}
+
+	tables, err := dumpTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string][]string, len(tables))
+	for _, table := range tables {
+		columns, err := tableColumns(ctx, table)
+		if err != nil {
+			return err
+		}
+		known[table] = columns
+	}
+
+	names := make([]string, 0, len(dump.Tables))
+	for table := range dump.Tables {
+		if _, ok := known[table]; !ok {
+			return fmt.Errorf("unknown table %s in dump", table)
+		}
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// rows are inserted table by table, foreign keys are checked once at commit
+	if _, err := tx.ExecContext(ctx, `PRAGMA defer_foreign_keys = ON`); err != nil {
+		return fmt.Errorf("failed to defer foreign keys: %w", err)
+	}
+
+	for _, table := range names {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", quoteIdent(table))); err != nil {
+			return fmt.Errorf("failed to clear table %s: %w", table, err)
+		}
+
+		columns := known[table]
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+		quoted := make([]string, len(columns))
+		for i, column := range columns {
+			quoted[i] = quoteIdent(column)
+		}
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(table), strings.Join(quoted, ", "), placeholders)
+
+		for i, record := range dump.Tables[table] {
+			args := make([]any, len(columns))
+			for j, column := range columns {
+				args[j] = importValue(record[column])
+			}
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("failed to import row %d of %s: %w", i, table, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// importValue converts decoded JSON back into SQLite values, numbers are decoded as json.Number to keep int64 IDs exact
+func importValue(value any) any {
+	n, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n.String()
+}
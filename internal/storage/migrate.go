@@ -0,0 +1,343 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"PakuchiBot/migrations"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrMigrationModified = errors.New("applied migration has been modified")
+	ErrMigrationMissing  = errors.New("applied migration is missing")
+	ErrNoDownMigration   = errors.New("migration has no down file")
+)
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+type Migration struct {
+	Version  string
+	Up       string
+	Down     string
+	Checksum string // sha256 of the up file
+}
+
+type MigrationStatus struct {
+	Version   string
+	Applied   bool
+	AppliedAt *time.Time
+	Modified  bool // the up file differs from the one that was applied
+	Missing   bool // applied, but no longer shipped with this binary
+	HasDown   bool
+}
+
+type appliedMigration struct {
+	Version   string    `db:"version"`
+	AppliedAt time.Time `db:"applied_at"`
+	Checksum  string    `db:"checksum"`
+}
+
+// loadMigrations reads the embedded migrations ordered by version, files without .up/.down are up migrations
+func loadMigrations() ([]Migration, error) {
+	files, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration files: %w", err)
+	}
+
+	byVersion := make(map[string]*Migration)
+	for _, file := range files {
+		content, err := fs.ReadFile(migrations.FS, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %w", file, err)
+		}
+
+		version, down := migrationVersion(file)
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version}
+			byVersion[version] = m
+		}
+
+		if down {
+			m.Down = string(content)
+		} else {
+			m.Up = string(content)
+			m.Checksum = checksum(content)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %s has a down file but no up file", m.Version)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+
+	return list, nil
+}
+
+func migrationVersion(file string) (version string, down bool) {
+	switch {
+	case strings.HasSuffix(file, downSuffix):
+		return strings.TrimSuffix(file, downSuffix), true
+	case strings.HasSuffix(file, upSuffix):
+		return strings.TrimSuffix(file, upSuffix), false
+	default:
+		return strings.TrimSuffix(file, ".sql"), false
+	}
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// ensureMigrationTable creates the migration log and upgrades logs written before checksums existed
+func ensureMigrationTable() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version TEXT PRIMARY KEY,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create migration log table: %w", err)
+	}
+
+	var hasChecksum bool
+	if err := db.Get(&hasChecksum, `SELECT COUNT(*) > 0 FROM pragma_table_info('schema_migrations') WHERE name = 'checksum'`); err != nil {
+		return fmt.Errorf("failed to inspect migration log table: %w", err)
+	}
+	if !hasChecksum {
+		if _, err := db.Exec(`ALTER TABLE schema_migrations ADD COLUMN checksum TEXT NOT NULL DEFAULT ''`); err != nil {
+			return fmt.Errorf("failed to add checksum to migration log table: %w", err)
+		}
+	}
+
+	// versions used to be recorded with the ".up" of the file name
+	if _, err := db.Exec(`UPDATE schema_migrations SET version = substr(version, 1, length(version) - 3) WHERE version LIKE '%.up'`); err != nil {
+		return fmt.Errorf("failed to normalize migration versions: %w", err)
+	}
+
+	return nil
+}
+
+func appliedMigrations() (map[string]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := db.Select(&rows, `SELECT version, applied_at, checksum FROM schema_migrations`); err != nil {
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+
+	applied := make(map[string]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// verifyChecksums fails when an applied migration was edited, migrations applied before
+// checksums were recorded are trusted and get their checksum filled in
+func verifyChecksums(list []Migration, applied map[string]appliedMigration) error {
+	for _, m := range list {
+		a, ok := applied[m.Version]
+		if !ok {
+			continue
+		}
+
+		if a.Checksum == "" {
+			if _, err := db.Exec(`UPDATE schema_migrations SET checksum = ? WHERE version = ?`, m.Checksum, m.Version); err != nil {
+				return fmt.Errorf("failed to record checksum of migration %s: %w", m.Version, err)
+			}
+			continue
+		}
+
+		if a.Checksum != m.Checksum {
+			return fmt.Errorf("%w: %s", ErrMigrationModified, m.Version)
+		}
+	}
+
+	return nil
+}
+
+// MigrateUp applies all pending migrations in order and returns the applied versions
+func MigrateUp() ([]string, error) {
+	if err := ensureMigrationTable(); err != nil {
+		return nil, err
+	}
+
+	list, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyChecksums(list, applied); err != nil {
+		return nil, err
+	}
+
+	var done []string
+	for _, m := range list {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return done, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+
+		if _, err := tx.Exec(m.Up); err != nil {
+			tx.Rollback()
+			return done, fmt.Errorf("failed to execute migration %s: %w", m.Version, err)
+		}
+
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version, checksum) VALUES (?, ?)", m.Version, m.Checksum); err != nil {
+			tx.Rollback()
+			return done, fmt.Errorf("failed to record migration version: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return done, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		logrus.WithField("version", m.Version).Info("migration applied")
+		done = append(done, m.Version)
+	}
+
+	return done, nil
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first, and returns the reverted versions
+func MigrateDown(steps int) ([]string, error) {
+	if err := ensureMigrationTable(); err != nil {
+		return nil, err
+	}
+
+	list, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyChecksums(list, applied); err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]Migration, len(list))
+	for _, m := range list {
+		known[m.Version] = m
+	}
+
+	versions := make([]string, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+
+	var done []string
+	for _, version := range versions {
+		if len(done) >= steps {
+			break
+		}
+
+		m, ok := known[version]
+		if !ok {
+			return done, fmt.Errorf("%w: %s", ErrMigrationMissing, version)
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("%w: %s", ErrNoDownMigration, version)
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return done, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+
+		if _, err := tx.Exec(m.Down); err != nil {
+			tx.Rollback()
+			return done, fmt.Errorf("failed to revert migration %s: %w", version, err)
+		}
+
+		if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", version); err != nil {
+			tx.Rollback()
+			return done, fmt.Errorf("failed to remove migration version: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return done, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		logrus.WithField("version", version).Info("migration reverted")
+		done = append(done, version)
+	}
+
+	return done, nil
+}
+
+// MigrationStatuses lists every known or applied migration ordered by version
+func MigrationStatuses() ([]MigrationStatus, error) {
+	if err := ensureMigrationTable(); err != nil {
+		return nil, err
+	}
+
+	list, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(list))
+	for _, m := range list {
+		status := MigrationStatus{Version: m.Version, HasDown: m.Down != ""}
+		if a, ok := applied[m.Version]; ok {
+			appliedAt := a.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = a.Checksum != "" && a.Checksum != m.Checksum
+			delete(applied, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, a := range applied {
+		appliedAt := a.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   a.Version,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
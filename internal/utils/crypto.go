@@ -6,25 +6,97 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
+	"regexp"
+	"strings"
 )
 
 var (
-	ErrInvalidKey  = errors.New("invalid encryption key")
-	ErrInvalidData = errors.New("invalid data")
-	ErrEncryption  = errors.New("encryption failed")
-	ErrDecryption  = errors.New("decryption failed")
+	ErrInvalidKey   = errors.New("invalid encryption key")
+	ErrInvalidKeyID = errors.New("invalid encryption key id")
+	ErrUnknownKeyID = errors.New("unknown encryption key id")
+	ErrInvalidData  = errors.New("invalid data")
+	ErrEncryption   = errors.New("encryption failed")
+	ErrDecryption   = errors.New("decryption failed")
 )
 
+// DefaultKeyID tags ciphertexts when no key ID is configured
+const DefaultKeyID = "default"
+
+// key IDs are limited to these characters, so "<id>:<base64>" always splits at the first ':'
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ValidKeyID reports whether id can be used to tag ciphertexts
+func ValidKeyID(id string) bool {
+	return keyIDPattern.MatchString(id)
+}
+
+type EncryptionKey struct {
+	ID  string `mapstructure:"id"`
+	Key string `mapstructure:"key"`
+}
+
+// TokenCrypto encrypts with the current key and decrypts with any key in the keyring.
+// Ciphertexts are stored as "<key id>:<base64>", untagged ones predate key versioning.
 type TokenCrypto struct {
-	key []byte
+	currentID string
+	keys      map[string][]byte
+	order     []string // current key first, tried in order for untagged ciphertexts
+}
+
+func NewTokenCrypto(current EncryptionKey, old ...EncryptionKey) (*TokenCrypto, error) {
+	tc := &TokenCrypto{keys: make(map[string][]byte)}
+
+	for i, key := range append([]EncryptionKey{current}, old...) {
+		if key.ID == "" {
+			key.ID = DefaultKeyID
+		}
+		if !ValidKeyID(key.ID) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidKeyID, key.ID)
+		}
+		if len(key.Key) != 32 {
+			return nil, fmt.Errorf("%w: key %q must be 32 bytes", ErrInvalidKey, key.ID)
+		}
+		if _, ok := tc.keys[key.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate key id %q", ErrInvalidKeyID, key.ID)
+		}
+		if i == 0 {
+			tc.currentID = key.ID
+		}
+		tc.keys[key.ID] = []byte(key.Key)
+		tc.order = append(tc.order, key.ID)
+	}
+
+	return tc, nil
+}
+
+// CurrentKeyID returns the ID new ciphertexts are tagged with
+func (tc *TokenCrypto) CurrentKeyID() string {
+	return tc.currentID
 }
 
-func NewTokenCrypto(key string) (*TokenCrypto, error) {
-	if len(key) != 32 {
-		return nil, ErrInvalidKey
+// KeyID returns the key ID a ciphertext is tagged with, empty for untagged ciphertexts
+func KeyID(encrypted string) string {
+	id, _, found := strings.Cut(encrypted, ":")
+	if !found {
+		return ""
 	}
-	return &TokenCrypto{key: []byte(key)}, nil
+	return id
+}
+
+// NeedsRotation reports whether a ciphertext was not encrypted with the current key
+func (tc *TokenCrypto) NeedsRotation(encrypted string) bool {
+	return KeyID(encrypted) != tc.currentID
+}
+
+// Reencrypt decrypts with whichever key matches and encrypts again with the current key
+func (tc *TokenCrypto) Reencrypt(encrypted string) (string, error) {
+	plaintext, err := tc.Decrypt(encrypted)
+	if err != nil {
+		return "", err
+	}
+	return tc.Encrypt(plaintext)
 }
 
 func (tc *TokenCrypto) Encrypt(plaintext string) (string, error) {
@@ -32,7 +104,7 @@ func (tc *TokenCrypto) Encrypt(plaintext string) (string, error) {
 		return "", ErrInvalidData
 	}
 
-	block, err := aes.NewCipher(tc.key)
+	block, err := aes.NewCipher(tc.keys[tc.currentID])
 	if err != nil {
 		return "", errors.Join(ErrEncryption, err)
 	}
@@ -48,7 +120,7 @@ func (tc *TokenCrypto) Encrypt(plaintext string) (string, error) {
 	}
 
 	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	return tc.currentID + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
 func (tc *TokenCrypto) Decrypt(encrypted string) (string, error) {
@@ -56,12 +128,34 @@ func (tc *TokenCrypto) Decrypt(encrypted string) (string, error) {
 		return "", ErrInvalidData
 	}
 
-	ciphertext, err := base64.StdEncoding.DecodeString(encrypted)
+	id, data, tagged := strings.Cut(encrypted, ":")
+	if tagged {
+		key, ok := tc.keys[id]
+		if !ok {
+			return "", fmt.Errorf("%w: %q", ErrUnknownKeyID, id)
+		}
+		return decryptWithKey(key, data)
+	}
+
+	// legacy ciphertext, the key that produced it is unknown
+	var lastErr error
+	for _, id := range tc.order {
+		plaintext, err := decryptWithKey(tc.keys[id], encrypted)
+		if err == nil {
+			return plaintext, nil
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+func decryptWithKey(key []byte, encoded string) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return "", errors.Join(ErrDecryption, err)
 	}
 
-	block, err := aes.NewCipher(tc.key)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", errors.Join(ErrDecryption, err)
 	}
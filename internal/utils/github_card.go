@@ -0,0 +1,337 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fogleman/gg"
+)
+
+const (
+	githubCardWidth    = 800
+	githubCardPadding  = 40
+	githubCardMaxTitle = 2
+	githubCardMaxBody  = 8
+)
+
+type GithubCardLabel struct {
+	Name  string
+	Color string // hex without '#', as returned by the GitHub API
+}
+
+type GithubCard struct {
+	Kind      string // e.g. Release, Pull Request, Issue
+	Repo      string // owner/name
+	Title     string
+	Author    string
+	AvatarURL string
+	State     string // open, closed, merged, released
+	Time      string
+	Labels    []GithubCardLabel
+	Body      string // markdown
+	URL       string // text fallback link sent along with the card
+
+	// diffstat, pull requests only
+	HasDiffStat  bool
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+}
+
+var (
+	mdImagePattern  = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	mdLinkPattern   = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdHTMLPattern   = regexp.MustCompile(`<[^>]+>`)
+	mdHeaderPattern = regexp.MustCompile(`^#{1,6}\s+`)
+	mdListPattern   = regexp.MustCompile(`^\s*([-*+]|\d+\.)\s+`)
+	mdEmphasis      = strings.NewReplacer("**", "", "__", "", "`", "", "~~", "")
+)
+
+// RenderMarkdownText converts markdown into plain lines suitable for drawing on a card
+func RenderMarkdownText(markdown string) []string {
+	lines := make([]string, 0)
+	inCodeBlock := false
+
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if !inCodeBlock {
+			line = mdImagePattern.ReplaceAllString(line, "")
+			line = mdLinkPattern.ReplaceAllString(line, "$1")
+			line = mdHTMLPattern.ReplaceAllString(line, "")
+			line = mdHeaderPattern.ReplaceAllString(strings.TrimSpace(line), "")
+			line = mdListPattern.ReplaceAllString(line, "• ")
+			line = strings.TrimPrefix(line, "> ")
+			line = mdEmphasis.Replace(line)
+		}
+		line = strings.TrimRight(line, " \t")
+		if line == "" && (len(lines) == 0 || lines[len(lines)-1] == "") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// GenerateGithubCard renders a GitHub release, issue or pull request as a PNG card
+func GenerateGithubCard(card GithubCard) ([]byte, error) {
+	contentWidth := float64(githubCardWidth - githubCardPadding*2)
+
+	measure := gg.NewContext(1, 1)
+	if err := measure.LoadFontFace("assets/fonts/MiSans/MiSans-Bold.ttf", 28); err != nil {
+		return nil, fmt.Errorf("failed to load font: %v", err)
+	}
+	titleLines := truncateLines(wrapText(measure, card.Title, contentWidth), githubCardMaxTitle)
+
+	if err := measure.LoadFontFace("assets/fonts/MiSans/MiSans-Regular.ttf", 18); err != nil {
+		return nil, fmt.Errorf("failed to load font: %v", err)
+	}
+	bodyLines := make([]string, 0)
+	for _, line := range RenderMarkdownText(card.Body) {
+		if line == "" {
+			bodyLines = append(bodyLines, "")
+			continue
+		}
+		bodyLines = append(bodyLines, wrapText(measure, line, contentWidth)...)
+	}
+	bodyLines = truncateLines(bodyLines, githubCardMaxBody)
+
+	height := 130 + len(titleLines)*38 + 40
+	if len(card.Labels) > 0 {
+		height += 40
+	}
+	if len(bodyLines) > 0 {
+		height += 20 + len(bodyLines)*28
+	}
+	if card.HasDiffStat {
+		height += 44
+	}
+	height += githubCardPadding
+
+	dc := gg.NewContext(githubCardWidth, height)
+	dc.SetHexColor("#f6f8fa")
+	dc.Clear()
+
+	dc.SetHexColor("#ffffff")
+	dc.DrawRoundedRectangle(12, 12, float64(githubCardWidth-24), float64(height-24), 12)
+	dc.Fill()
+
+	// header: avatar, repository and kind
+	avatarImg, err := loadAndResizeAvatar(card.AvatarURL, 64)
+	if err == nil {
+		dc.DrawImage(avatarImg, githubCardPadding, githubCardPadding)
+	}
+
+	if err := dc.LoadFontFace("assets/fonts/MiSans/MiSans-Medium.ttf", 22); err != nil {
+		return nil, fmt.Errorf("failed to load font: %v", err)
+	}
+	dc.SetHexColor("#24292f")
+	dc.DrawString(card.Repo, githubCardPadding+84, githubCardPadding+28)
+
+	if err := dc.LoadFontFace("assets/fonts/MiSans/MiSans-Regular.ttf", 18); err != nil {
+		return nil, fmt.Errorf("failed to load font: %v", err)
+	}
+	dc.SetHexColor("#57606a")
+	dc.DrawString(card.Kind, githubCardPadding+84, githubCardPadding+56)
+
+	if card.State != "" {
+		drawPill(dc, card.State, stateColor(card.State), float64(githubCardWidth-githubCardPadding), githubCardPadding+12, true)
+	}
+
+	// title
+	y := float64(130)
+	if err := dc.LoadFontFace("assets/fonts/MiSans/MiSans-Bold.ttf", 28); err != nil {
+		return nil, fmt.Errorf("failed to load font: %v", err)
+	}
+	dc.SetHexColor("#24292f")
+	for _, line := range titleLines {
+		y += 38
+		dc.DrawString(line, githubCardPadding, y)
+	}
+
+	// author and time
+	if err := dc.LoadFontFace("assets/fonts/MiSans/MiSans-Regular.ttf", 18); err != nil {
+		return nil, fmt.Errorf("failed to load font: %v", err)
+	}
+	y += 36
+	dc.SetHexColor("#57606a")
+	dc.DrawString(strings.TrimSpace(fmt.Sprintf("%s  %s", card.Author, card.Time)), githubCardPadding, y)
+
+	// labels
+	if len(card.Labels) > 0 {
+		if err := dc.LoadFontFace("assets/fonts/MiSans/MiSans-Medium.ttf", 15); err != nil {
+			return nil, fmt.Errorf("failed to load font: %v", err)
+		}
+		y += 14
+		x := float64(githubCardPadding)
+		for _, label := range card.Labels {
+			w, _ := dc.MeasureString(label.Name)
+			if x+w+20 > float64(githubCardWidth-githubCardPadding) {
+				break
+			}
+			drawPill(dc, label.Name, "#"+label.Color, x, y, false)
+			x += w + 30
+		}
+		y += 26
+	}
+
+	// body
+	if len(bodyLines) > 0 {
+		y += 20
+		dc.SetHexColor("#d0d7de")
+		dc.DrawLine(githubCardPadding, y-6, float64(githubCardWidth-githubCardPadding), y-6)
+		dc.Stroke()
+
+		if err := dc.LoadFontFace("assets/fonts/MiSans/MiSans-Regular.ttf", 18); err != nil {
+			return nil, fmt.Errorf("failed to load font: %v", err)
+		}
+		dc.SetHexColor("#24292f")
+		for _, line := range bodyLines {
+			y += 28
+			dc.DrawString(line, githubCardPadding, y)
+		}
+	}
+
+	// diffstat
+	if card.HasDiffStat {
+		if err := dc.LoadFontFace("assets/fonts/MiSans/MiSans-Medium.ttf", 18); err != nil {
+			return nil, fmt.Errorf("failed to load font: %v", err)
+		}
+		y += 40
+		x := float64(githubCardPadding)
+		additions := fmt.Sprintf("+%d", card.Additions)
+		deletions := fmt.Sprintf("-%d", card.Deletions)
+		files := fmt.Sprintf("%d 个文件变更", card.ChangedFiles)
+
+		dc.SetHexColor("#1a7f37")
+		dc.DrawString(additions, x, y)
+		w, _ := dc.MeasureString(additions)
+		x += w + 12
+
+		dc.SetHexColor("#cf222e")
+		dc.DrawString(deletions, x, y)
+		w, _ = dc.MeasureString(deletions)
+		x += w + 20
+
+		dc.SetHexColor("#57606a")
+		dc.DrawString(files, x, y)
+	}
+
+	var buf bytes.Buffer
+	if err := dc.EncodePNG(&buf); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// wrapText breaks text by rune width, since gg's WordWrap only splits on spaces and CJK text has none
+func wrapText(dc *gg.Context, text string, width float64) []string {
+	lines := make([]string, 0)
+	current := make([]rune, 0)
+
+	for _, r := range text {
+		candidate := string(append(current, r))
+		if w, _ := dc.MeasureString(candidate); w > width && len(current) > 0 {
+			if r == ' ' {
+				lines = append(lines, string(current))
+				current = current[:0]
+				continue
+			}
+			// keep latin words together by breaking at the last space when there is one
+			tail := make([]rune, 0)
+			if r < unicode.MaxLatin1 {
+				if i := lastSpace(current); i > 0 {
+					tail = append(tail, current[i+1:]...)
+					current = current[:i]
+				}
+			}
+			lines = append(lines, string(current))
+			current = append(current[:0], tail...)
+		}
+		current = append(current, r)
+	}
+
+	if len(current) > 0 {
+		lines = append(lines, string(current))
+	}
+
+	return lines
+}
+
+func lastSpace(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == ' ' {
+			return i
+		}
+		if runes[i] >= unicode.MaxLatin1 {
+			return -1
+		}
+	}
+	return -1
+}
+
+func truncateLines(lines []string, max int) []string {
+	if len(lines) <= max {
+		return lines
+	}
+	lines = lines[:max]
+	lines[max-1] = strings.TrimRight(lines[max-1], " ") + "…"
+	return lines
+}
+
+// drawPill draws a rounded badge, alignRight makes x the right edge instead of the left
+func drawPill(dc *gg.Context, text, color string, x, y float64, alignRight bool) {
+	w, h := dc.MeasureString(text)
+	pillW, pillH := w+20, h+12
+	if alignRight {
+		x -= pillW
+	}
+
+	dc.SetHexColor(color)
+	dc.DrawRoundedRectangle(x, y, pillW, pillH, pillH/2)
+	dc.Fill()
+
+	if isLightColor(color) {
+		dc.SetHexColor("#24292f")
+	} else {
+		dc.SetHexColor("#ffffff")
+	}
+	dc.DrawStringAnchored(text, x+pillW/2, y+pillH/2, 0.5, 0.35)
+}
+
+func stateColor(state string) string {
+	switch state {
+	case "open", "released":
+		return "#1a7f37"
+	case "merged":
+		return "#8250df"
+	case "closed":
+		return "#cf222e"
+	default:
+		return "#57606a"
+	}
+}
+
+func isLightColor(hex string) bool {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		return false
+	}
+	r, _ := strconv.ParseInt(hex[0:2], 16, 64)
+	g, _ := strconv.ParseInt(hex[2:4], 16, 64)
+	b, _ := strconv.ParseInt(hex[4:6], 16, 64)
+	return (r*299+g*587+b*114)/1000 > 150
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/fogleman/gg"
+)
+
+const (
+	heatmapPadding  = 30
+	heatmapCell     = 16
+	heatmapGap      = 4
+	heatmapLabelW   = 36
+	heatmapMinWidth = 480
+)
+
+// SignHeatmapLevel is the color of a day on the heatmap
+type SignHeatmapLevel int
+
+const (
+	SignHeatmapNone SignHeatmapLevel = iota
+	SignHeatmapSuccess
+	SignHeatmapManual
+	SignHeatmapFailed
+)
+
+type SignHeatmap struct {
+	Title string
+	Start time.Time                   // first day shown
+	End   time.Time                   // last day shown, usually today
+	Days  map[string]SignHeatmapLevel // "2006-01-02" -> level, missing days have no record
+	Stats []string                    // lines drawn under the title
+}
+
+var heatmapColors = map[SignHeatmapLevel]string{
+	SignHeatmapNone:    "#ebedf0",
+	SignHeatmapSuccess: "#40c463",
+	SignHeatmapManual:  "#54aeff",
+	SignHeatmapFailed:  "#ff8182",
+}
+
+var heatmapWeekdays = []string{"一", "", "三", "", "五", "", "日"}
+
+// GenerateSignHeatmap draws one column per week with Monday on top, like a GitHub contribution graph
+func GenerateSignHeatmap(h SignHeatmap) ([]byte, error) {
+	start := dateOnly(h.Start)
+	end := dateOnly(h.End)
+	if end.Before(start) {
+		return nil, fmt.Errorf("heatmap ends before it starts")
+	}
+
+	// the first column starts on the Monday of the first week
+	first := start.AddDate(0, 0, -weekdayIndex(start))
+	weeks := int(end.Sub(first).Hours()/24)/7 + 1
+
+	gridW := heatmapLabelW + weeks*(heatmapCell+heatmapGap)
+	width := max(gridW+heatmapPadding*2, heatmapMinWidth)
+	height := heatmapPadding + 36 + len(h.Stats)*26 + 16 + 20 + 7*(heatmapCell+heatmapGap) + 16 + 20 + heatmapPadding
+
+	dc := gg.NewContext(width, height)
+	dc.SetHexColor("#f6f8fa")
+	dc.Clear()
+
+	dc.SetHexColor("#ffffff")
+	dc.DrawRoundedRectangle(8, 8, float64(width-16), float64(height-16), 12)
+	dc.Fill()
+
+	y := float64(heatmapPadding)
+
+	if err := dc.LoadFontFace("assets/fonts/MiSans/MiSans-Bold.ttf", 24); err != nil {
+		return nil, fmt.Errorf("failed to load font: %v", err)
+	}
+	dc.SetHexColor("#24292f")
+	dc.DrawStringAnchored(h.Title, heatmapPadding, y, 0, 0.8)
+	y += 36
+
+	if err := dc.LoadFontFace("assets/fonts/MiSans/MiSans-Regular.ttf", 16); err != nil {
+		return nil, fmt.Errorf("failed to load font: %v", err)
+	}
+	dc.SetHexColor("#57606a")
+	for _, line := range h.Stats {
+		dc.DrawStringAnchored(line, heatmapPadding, y, 0, 0.8)
+		y += 26
+	}
+	y += 16
+
+	if err := dc.LoadFontFace("assets/fonts/MiSans/MiSans-Regular.ttf", 12); err != nil {
+		return nil, fmt.Errorf("failed to load font: %v", err)
+	}
+
+	gridX := float64(heatmapPadding + heatmapLabelW)
+	gridY := y + 20
+	step := float64(heatmapCell + heatmapGap)
+
+	// month labels above the week in which a month starts
+	dc.SetHexColor("#57606a")
+	for week := 0; week < weeks; week++ {
+		for i := 0; i < 7; i++ {
+			day := first.AddDate(0, 0, week*7+i)
+			if day.Day() == 1 && !day.Before(start) && !day.After(end) {
+				dc.DrawStringAnchored(fmt.Sprintf("%d月", day.Month()), gridX+float64(week)*step, y, 0, 0.8)
+			}
+		}
+	}
+	for i, label := range heatmapWeekdays {
+		dc.DrawStringAnchored(label, heatmapPadding, gridY+float64(i)*step+heatmapCell/2, 0, 0.35)
+	}
+
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		week := int(day.Sub(first).Hours()/24) / 7
+		x := gridX + float64(week)*step
+		cellY := gridY + float64(weekdayIndex(day))*step
+
+		dc.SetHexColor(heatmapColors[h.Days[day.Format("2006-01-02")]])
+		dc.DrawRoundedRectangle(x, cellY, heatmapCell, heatmapCell, 3)
+		dc.Fill()
+	}
+
+	// legend
+	y = gridY + 7*step + 16
+	x := float64(heatmapPadding)
+	for _, item := range []struct {
+		level SignHeatmapLevel
+		text  string
+	}{
+		{SignHeatmapSuccess, "自动签到"},
+		{SignHeatmapManual, "手动签到"},
+		{SignHeatmapFailed, "签到失败"},
+		{SignHeatmapNone, "无记录"},
+	} {
+		dc.SetHexColor(heatmapColors[item.level])
+		dc.DrawRoundedRectangle(x, y, 12, 12, 2)
+		dc.Fill()
+		x += 18
+
+		dc.SetHexColor("#57606a")
+		dc.DrawStringAnchored(item.text, x, y+6, 0, 0.35)
+		w, _ := dc.MeasureString(item.text)
+		x += w + 18
+	}
+
+	var buf bytes.Buffer
+	if err := dc.EncodePNG(&buf); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// weekdayIndex counts from Monday
+func weekdayIndex(t time.Time) int {
+	return (int(t.Weekday()) + 6) % 7
+}
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -11,10 +12,9 @@ import (
 
 	"PakuchiBot/internal/bot"
 	"PakuchiBot/internal/handler"
-	"PakuchiBot/internal/repository"
+	"PakuchiBot/internal/mgclub"
 	"PakuchiBot/internal/scheduler"
 	"PakuchiBot/internal/storage"
-	"PakuchiBot/internal/utils"
 
 	zero "github.com/wdvxdr1123/ZeroBot"
 )
@@ -27,6 +27,18 @@ func main() {
 		}
 	}()
 
+	args := os.Args[1:]
+	if len(args) == 0 {
+		args = []string{"run"}
+	}
+
+	if err := runCommand(args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runBot connects to OneBot and serves until SIGINT or SIGTERM
+func runBot() {
 	if err := bot.InitConfig(); err != nil {
 		log.Fatalf("failed to init config: %v", err)
 	}
@@ -37,44 +49,88 @@ func main() {
 
 	time.Sleep(2 * time.Second)
 
-	crypto, err := utils.NewTokenCrypto(bot.Config.Storage.EncryptionKey)
-	if err != nil {
-		log.Fatalf("failed to create crypto tool: %v", err)
-	}
+	jobs := scheduler.NewScheduler(bot.JobRepo, bot.Config.Location())
 
-	scheduler := scheduler.NewScheduler(
-		repository.NewUserRepository(bot.DB),
-		repository.NewSignRepository(bot.DB),
-		repository.NewNotifyRepository(bot.DB),
-		crypto,
+	signTask := scheduler.NewSignTask(
+		bot.UserRepo,
+		bot.SignRepo,
+		bot.NotifyRepo,
+		bot.TokenCrypto,
 		zero.GetBot(bot.Config.Bot.SelfID),
-		time.Duration(bot.Config.Scheduler.CheckInterval)*time.Second,
 		bot.Config.Scheduler.MaxRetries,
+		bot.Config.Scheduler.SignConcurrency,
+		bot.Config.DefaultSignWindow(),
+		bot.Config.Location(),
 	)
+	mgclub.SetRateLimit(bot.Config.Scheduler.SignRateLimit)
+	if err := jobs.Register(signTask.Job(), signJobSpec(&bot.Config)); err != nil {
+		log.Fatalf("failed to schedule sign-in: %v", err)
+	}
 
-	scheduler.Start()
-	defer scheduler.Stop()
+	cleanupTask := scheduler.NewCleanupTask(bot.GithubCursorRepo, bot.GithubDigestRepo)
+	if err := jobs.Register(cleanupTask.Job(), cleanupJobSpec(&bot.Config)); err != nil {
+		log.Fatalf("failed to schedule database cleanup: %v", err)
+	}
+
+	jobs.Start()
+
+	bot.OnReload(func(_, cfg *bot.BotConfig) {
+		jobs.SetLocation(cfg.Location())
+		bot.SignRepo.SetLocation(cfg.Location())
+		signTask.Reconfigure(cfg.Scheduler.MaxRetries, cfg.Scheduler.SignConcurrency, cfg.DefaultSignWindow(), cfg.Location())
+		mgclub.SetRateLimit(cfg.Scheduler.SignRateLimit)
+		for name, spec := range map[string]string{
+			scheduler.JobMGClubSign: signJobSpec(cfg),
+			scheduler.JobDBCleanup:  cleanupJobSpec(cfg),
+		} {
+			if err := jobs.Reschedule(name, spec); err != nil {
+				log.Printf("failed to reschedule job %s: %v", name, err)
+			}
+		}
+	})
+
+	// group plugin switches, registered first so the other plugins' rules see the loaded settings
+	handler.RegisterPluginHandler()
+
+	handler.RegisterJobsHandler(jobs)
 
 	// Default Plugin
 	handler.RegisterPingHandler()
 	handler.RegisterLuckHandler()
 
 	// MGClub
-	mgHandler := handler.NewMGClubHandler(bot.UserRepo, bot.NotifyRepo, bot.TokenCrypto)
+	mgHandler := handler.NewMGClubHandler(bot.UserRepo, bot.SignRepo, bot.NotifyRepo, bot.TokenCrypto)
 	mgHandler.Register()
 
 	// Github Notifier
-	handler.RegisterGitHubHandler()
+	handler.RegisterGitHubHandler(jobs)
 
 	handler.RegisterHumanLikeHandler()
 
+	bot.WatchConfig()
+
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	<-sig
 
+	// running jobs still use the database
+	jobs.Stop()
+
 	if err := storage.CloseDB(); err != nil {
 		log.Printf("failed to close db: %v\n", err)
 	}
 
 	log.Println("program exited")
 }
+
+// defaultCleanupSpec prunes the database at night when the bot is quiet
+const defaultCleanupSpec = "30 4 * * *"
+
+// signJobSpec checks for due sign-ins every scheduler.check_interval seconds unless scheduler.jobs sets an expression
+func signJobSpec(cfg *bot.BotConfig) string {
+	return cfg.JobSpec(scheduler.JobMGClubSign, fmt.Sprintf("@every %ds", cfg.Scheduler.CheckInterval))
+}
+
+func cleanupJobSpec(cfg *bot.BotConfig) string {
+	return cfg.JobSpec(scheduler.JobDBCleanup, defaultCleanupSpec)
+}
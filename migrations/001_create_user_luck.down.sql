@@ -0,0 +1,2 @@
+-- 删除用户人品表
+DROP TABLE IF EXISTS user_luck;
@@ -0,0 +1,3 @@
+-- 删除用户表
+DROP TRIGGER IF EXISTS update_mgclub_users_timestamp;
+DROP TABLE IF EXISTS mgclub_users;
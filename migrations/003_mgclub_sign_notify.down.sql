@@ -0,0 +1,3 @@
+-- 删除用户通知设置表
+DROP TRIGGER IF EXISTS update_mgclub_notify_settings_timestamp;
+DROP TABLE IF EXISTS mgclub_notify_settings;
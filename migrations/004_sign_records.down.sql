@@ -0,0 +1,3 @@
+-- 删除签到记录表
+DROP TRIGGER IF EXISTS update_sign_records_timestamp;
+DROP TABLE IF EXISTS sign_records;
@@ -0,0 +1,3 @@
+-- 删除GitHub订阅表
+DROP TRIGGER IF EXISTS update_github_subscriptions_timestamp;
+DROP TABLE IF EXISTS github_subscriptions;
@@ -0,0 +1,17 @@
+-- 创建GitHub订阅表
+CREATE TABLE IF NOT EXISTS github_subscriptions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    target_type TEXT NOT NULL, -- group, private
+    target_id INTEGER NOT NULL,
+    repo TEXT NOT NULL,        -- owner/name
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(target_type, target_id, repo)
+);
+
+-- 创建更新时间触发器
+CREATE TRIGGER IF NOT EXISTS update_github_subscriptions_timestamp 
+AFTER UPDATE ON github_subscriptions
+BEGIN
+    UPDATE github_subscriptions SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
+END;
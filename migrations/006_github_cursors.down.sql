@@ -0,0 +1,4 @@
+-- 删除GitHub通知游标表和已推送事件表
+DROP INDEX IF EXISTS idx_github_delivered_events_created_at;
+DROP TABLE IF EXISTS github_delivered_events;
+DROP TABLE IF EXISTS github_cursors;
@@ -0,0 +1,20 @@
+-- 创建GitHub通知游标表
+CREATE TABLE IF NOT EXISTS github_cursors (
+    repo TEXT NOT NULL,         -- owner/name
+    monitor_type TEXT NOT NULL, -- commit, release, issue, pr
+    last_id TEXT NOT NULL DEFAULT '', -- commit: 最后一次提交的SHA, release: 最新版本ID
+    last_time DATETIME NOT NULL,      -- 最后一次处理的事件时间
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (repo, monitor_type)
+);
+
+-- 创建已推送事件表，用于去重
+CREATE TABLE IF NOT EXISTS github_delivered_events (
+    repo TEXT NOT NULL,
+    monitor_type TEXT NOT NULL,
+    event_key TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (repo, monitor_type, event_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_delivered_events_created_at ON github_delivered_events(created_at);
@@ -0,0 +1,3 @@
+-- 删除运行时添加的GitHub监控仓库表
+DROP TRIGGER IF EXISTS update_github_repositories_timestamp;
+DROP TABLE IF EXISTS github_repositories;
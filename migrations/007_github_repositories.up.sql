@@ -0,0 +1,18 @@
+-- 创建运行时添加的GitHub监控仓库表
+CREATE TABLE IF NOT EXISTS github_repositories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner TEXT NOT NULL COLLATE NOCASE,
+    name TEXT NOT NULL COLLATE NOCASE,
+    monitor_type TEXT NOT NULL, -- 逗号分隔，例如 commit,release,pr
+    created_by INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(owner, name)
+);
+
+-- 创建更新时间触发器
+CREATE TRIGGER IF NOT EXISTS update_github_repositories_timestamp 
+AFTER UPDATE ON github_repositories
+BEGIN
+    UPDATE github_repositories SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
+END;
@@ -0,0 +1,4 @@
+-- 移除GitHub订阅的过滤字段
+ALTER TABLE github_subscriptions DROP COLUMN labels;
+ALTER TABLE github_subscriptions DROP COLUMN branches;
+ALTER TABLE github_subscriptions DROP COLUMN events;
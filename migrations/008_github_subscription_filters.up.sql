@@ -0,0 +1,4 @@
+-- 为GitHub订阅添加事件、分支和标签过滤（逗号分隔，为空表示不过滤）
+ALTER TABLE github_subscriptions ADD COLUMN events TEXT NOT NULL DEFAULT '';
+ALTER TABLE github_subscriptions ADD COLUMN branches TEXT NOT NULL DEFAULT '';
+ALTER TABLE github_subscriptions ADD COLUMN labels TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- 删除GitHub通知目标设置表
+DROP TABLE IF EXISTS github_target_settings;
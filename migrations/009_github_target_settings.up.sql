@@ -0,0 +1,9 @@
+-- 创建GitHub通知目标设置表
+CREATE TABLE IF NOT EXISTS github_target_settings (
+    target_type TEXT NOT NULL, -- group, private
+    target_id INTEGER NOT NULL,
+    format TEXT NOT NULL DEFAULT 'text', -- text, image
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (target_type, target_id)
+);
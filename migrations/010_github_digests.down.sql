@@ -0,0 +1,4 @@
+-- 删除GitHub摘要相关表
+DROP TABLE IF EXISTS github_digests;
+DROP INDEX IF EXISTS idx_github_events_occurred_at;
+DROP TABLE IF EXISTS github_events;
@@ -0,0 +1,25 @@
+-- 创建GitHub事件记录表，用于生成每日/每周摘要
+CREATE TABLE IF NOT EXISTS github_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    repo TEXT NOT NULL,         -- owner/name
+    event_type TEXT NOT NULL,   -- commit, release, issue, pr
+    action TEXT NOT NULL DEFAULT '', -- opened, closed, merged, updated, published, pushed
+    branch TEXT NOT NULL DEFAULT '',
+    labels TEXT NOT NULL DEFAULT '', -- 逗号分隔
+    title TEXT NOT NULL DEFAULT '',
+    author TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL DEFAULT '',
+    occurred_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_events_occurred_at ON github_events(occurred_at);
+
+-- 创建通知目标摘要设置表
+CREATE TABLE IF NOT EXISTS github_digests (
+    target_type TEXT NOT NULL, -- group, private
+    target_id INTEGER NOT NULL,
+    mode TEXT,                 -- off, daily, weekly; 为空时使用配置文件
+    last_sent_at DATETIME,     -- 上一次发送摘要的时间
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (target_type, target_id)
+);
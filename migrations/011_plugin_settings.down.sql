@@ -0,0 +1,2 @@
+-- 删除插件开关表
+DROP TABLE IF EXISTS plugin_settings;
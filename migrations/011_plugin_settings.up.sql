@@ -0,0 +1,9 @@
+-- 创建插件开关表，group_id 为 0 表示超级用户设置的全局默认值
+CREATE TABLE IF NOT EXISTS plugin_settings (
+    group_id INTEGER NOT NULL,
+    plugin TEXT NOT NULL,      -- jrrp, mgclub, github, humanlike
+    enabled INTEGER NOT NULL,  -- 0: 关闭, 1: 开启
+    updated_by INTEGER NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, plugin)
+);
@@ -0,0 +1,3 @@
+-- 移除自动签到时间段
+ALTER TABLE sign_records DROP COLUMN scheduled_at;
+ALTER TABLE mgclub_users DROP COLUMN sign_window;
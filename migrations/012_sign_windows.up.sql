@@ -0,0 +1,5 @@
+-- 为用户添加自动签到时间段（HH:MM-HH:MM，为空表示使用配置文件中的默认时间段）
+ALTER TABLE mgclub_users ADD COLUMN sign_window TEXT NOT NULL DEFAULT '';
+
+-- 为签到记录添加当天随机选定的签到时间，为空表示尽快签到
+ALTER TABLE sign_records ADD COLUMN scheduled_at DATETIME;
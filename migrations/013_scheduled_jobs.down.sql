@@ -0,0 +1,2 @@
+-- 删除定时任务表
+DROP TABLE IF EXISTS scheduled_jobs;
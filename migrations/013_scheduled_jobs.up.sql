@@ -0,0 +1,11 @@
+-- 创建定时任务表，记录每个任务的调度表达式、上一次运行结果和下一次运行时间
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name TEXT PRIMARY KEY,                -- mgclub_sign, github_poll, github_digest, db_cleanup
+    spec TEXT NOT NULL,                   -- cron 表达式
+    last_started_at DATETIME,
+    last_finished_at DATETIME,
+    last_status TEXT NOT NULL DEFAULT '', -- running, success, failed, interrupted; 为空表示从未运行
+    last_error TEXT NOT NULL DEFAULT '',
+    next_run_at DATETIME,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+-- 删除签到失败原因和 token 失效标记
+ALTER TABLE mgclub_users DROP COLUMN token_invalid_at;
+ALTER TABLE sign_records DROP COLUMN next_retry_at;
+ALTER TABLE sign_records DROP COLUMN failure_reason;
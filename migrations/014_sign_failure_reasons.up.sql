@@ -0,0 +1,6 @@
+-- 记录签到失败原因，并按指数退避安排下一次重试
+ALTER TABLE sign_records ADD COLUMN failure_reason TEXT NOT NULL DEFAULT ''; -- network, server, auth, decrypt, unknown
+ALTER TABLE sign_records ADD COLUMN next_retry_at DATETIME;                  -- 本地时间，为空表示可以立即重试
+
+-- token 失效的时间，重新绑定 token 后清空，期间不再自动签到
+ALTER TABLE mgclub_users ADD COLUMN token_invalid_at DATETIME;
@@ -0,0 +1,4 @@
+-- 删除签到经验和手动签到标记
+DROP INDEX IF EXISTS idx_sign_records_status_date;
+ALTER TABLE sign_records DROP COLUMN manual;
+ALTER TABLE sign_records DROP COLUMN exp;
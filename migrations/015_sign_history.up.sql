@@ -0,0 +1,6 @@
+-- 记录每次签到获得的经验，以及是否由用户使用 /255sign 手动签到
+ALTER TABLE sign_records ADD COLUMN exp INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sign_records ADD COLUMN manual INTEGER NOT NULL DEFAULT 0; -- 0: 自动签到, 1: 手动签到
+
+-- 签到历史和排行榜按日期查询成功的记录
+CREATE INDEX IF NOT EXISTS idx_sign_records_status_date ON sign_records (status, sign_date);
@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL schema migrations into the binary.
+//
+// Each migration is a NNN_name.up.sql file with an optional NNN_name.down.sql that reverts it.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS